@@ -0,0 +1,96 @@
+package amatriciana
+
+//Adjudication says when a game that isn't over yet gets called anyway.
+//the scores are the ones the players give for their moves
+type Adjudication struct {
+	//a side loses when the score has been at least ResignScore against it
+	//for ResignPlies plies in a row. 0 plies means never
+	ResignScore Score
+	ResignPlies int
+	//it's a draw when the score has been within DrawScore of zero for
+	//DrawPlies plies in a row, DrawAfter plies into the game. 0 plies means never
+	DrawScore Score
+	DrawPlies int
+	DrawAfter int
+	//Tablebases ends the game with the tablebases' result as soon as they know it
+	Tablebases bool
+}
+
+//DefaultAdjudication calls a game when both sides have agreed on it for a
+//few moves, and as soon as the tablebases know how it ends
+func DefaultAdjudication() Adjudication {
+	return Adjudication{
+		ResignScore: knownWin,
+		ResignPlies: 8,
+		DrawScore:   10,
+		DrawPlies:   12,
+		DrawAfter:   80,
+		Tablebases:  true,
+	}
+}
+
+//keeps count of the plies in a row that fit the rules
+type adjudicator struct {
+	Adjudication
+	//positive when white is winning, negative when black is
+	winning int
+	drawing int
+}
+
+//update gets the score of the move played at ply, from white's point of view.
+//a side that's winning keeps the same sign whoever is moving, so winning only
+//grows when both players agree
+func (a *adjudicator) update(ply int, score Score) (string, bool) {
+	switch {
+	case score >= a.ResignScore:
+		if a.winning < 0 {
+			a.winning = 0
+		}
+		a.winning++
+	case score <= -a.ResignScore:
+		if a.winning > 0 {
+			a.winning = 0
+		}
+		a.winning--
+	default:
+		a.winning = 0
+	}
+
+	if ply >= a.DrawAfter && score <= a.DrawScore && score >= -a.DrawScore {
+		a.drawing++
+	} else {
+		a.drawing = 0
+	}
+
+	switch {
+	case a.ResignPlies > 0 && a.winning >= a.ResignPlies:
+		return "1-0", true
+	case a.ResignPlies > 0 && a.winning <= -a.ResignPlies:
+		return "0-1", true
+	case a.DrawPlies > 0 && a.drawing >= a.DrawPlies:
+		return "1/2-1/2", true
+	}
+	return "", false
+}
+
+//tablebase is the result the tablebases know for the position. they only
+//know about the fifty move rule right after a capture or a pawn move, like
+//in the search
+func (a *adjudicator) tablebase(b Board) (string, bool) {
+	if !a.Tablebases || b.halfMoves != 0 {
+		return "", false
+	}
+
+	wdl, err := b.ProbeWDL()
+	if err != nil {
+		return "", false
+	}
+
+	switch {
+	case wdl == WDLWin && b.turn == white, wdl == WDLLoss && b.turn == black:
+		return "1-0", true
+	case wdl == WDLWin, wdl == WDLLoss:
+		return "0-1", true
+	}
+	return "1/2-1/2", true
+}
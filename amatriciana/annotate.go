@@ -0,0 +1,194 @@
+package amatriciana
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+//the numeric annotation glyphs the review puts on bad moves
+const (
+	nagMistake    = 2
+	nagBlunder    = 4
+	nagInaccuracy = 6
+)
+
+//AnnotateOptions is how a game gets reviewed
+type AnnotateOptions struct {
+	//Search is used on every position of the game. with a Table the
+	//positions share it, which is faster since they follow each other
+	Search SearchOptions
+	//the centipawns a move has to lose to be an inaccuracy (?!), a
+	//mistake (?) or a blunder (??). 0 leaves that kind of move alone
+	Inaccuracy int
+	Mistake    int
+	Blunder    int
+	//Progress gets called after every position that got searched
+	Progress func(searched, positions int)
+}
+
+//DefaultAnnotateOptions searches every position for a second and calls
+//50, 100 and 300 centipawns an inaccuracy, a mistake and a blunder
+func DefaultAnnotateOptions() AnnotateOptions {
+	return AnnotateOptions{
+		Search:     SearchOptions{MoveTime: time.Second},
+		Inaccuracy: 50,
+		Mistake:    100,
+		Blunder:    300,
+	}
+}
+
+//PlayerReview is how a player did in a game
+type PlayerReview struct {
+	Moves int
+	//AverageCentipawnLoss is how much worse than the best move the moves
+	//were on average, scores over a knownWin count as a knownWin
+	AverageCentipawnLoss float64
+	//Accuracy goes from 0 to 100, it's the average of the accuracy of every
+	//move worked out from the winning chances it lost, like lichess does it
+	Accuracy     float64
+	Inaccuracies int
+	Mistakes     int
+	Blunders     int
+}
+
+//GameReview is a game with the review of its moves
+type GameReview struct {
+	//Game is the game with annotations on the moves that lost something:
+	//the glyph, a comment with the scores and the best line as a variation,
+	//added after the annotations the game already had
+	Game Game
+	//Scores are the scores of the positions of the game from white's point
+	//of view, one more than the moves
+	Scores []Score
+	//Losses are the centipawns every move lost
+	Losses []int
+	White  PlayerReview
+	Black  PlayerReview
+}
+
+//Annotate searches every position of the game and marks the moves that lost
+//too much compared to the best one
+func (g Game) Annotate(opts AnnotateOptions) GameReview {
+	positions := make([]Board, 0, len(g.Moves)+1)
+	board := g.Start.Clone()
+	positions = append(positions, board.Clone())
+	for _, m := range g.Moves {
+		board.move(m)
+		positions = append(positions, board.Clone())
+	}
+
+	//the score of every position for the side to move, and the line the search likes
+	scores := make([]Score, len(positions))
+	lines := make([]SearchLine, len(positions))
+	for i, position := range positions {
+		found := position.Search(opts.Search)
+		switch {
+		case len(found) > 0:
+			lines[i] = found[0]
+			scores[i] = found[0].Score
+		case position.isKingInCheck(position.turn):
+			scores[i] = -mateScore
+		default:
+			scores[i] = 0
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(positions))
+		}
+	}
+
+	review := GameReview{
+		Game:   g,
+		Scores: make([]Score, len(positions)),
+		Losses: make([]int, len(g.Moves)),
+	}
+	for i, position := range positions {
+		review.Scores[i] = scores[i]
+		if position.turn == black {
+			review.Scores[i] = -scores[i]
+		}
+	}
+
+	//the annotations the game already has stay, the review adds to them.
+	//they're copied so the caller's game doesn't change
+	review.Game.Annotations = make([]Annotation, len(g.Moves))
+	copy(review.Game.Annotations, g.Annotations)
+	var accuracies [2]float64
+	for i, m := range g.Moves {
+		before := capScore(scores[i])
+		after := capScore(-scores[i+1])
+		best := lines[i].Move
+		if m == best {
+			after = before
+		}
+		loss := maxInt(0, before-after)
+		review.Losses[i] = loss
+
+		player := &review.White
+		if m.color == black {
+			player = &review.Black
+		}
+		player.Moves++
+		player.AverageCentipawnLoss += float64(loss)
+		accuracies[m.color.index()] += moveAccuracy(before, after)
+
+		var nag int
+		var verdict string
+		switch {
+		case opts.Blunder > 0 && loss >= opts.Blunder:
+			nag, verdict = nagBlunder, "Blunder"
+			player.Blunders++
+		case opts.Mistake > 0 && loss >= opts.Mistake:
+			nag, verdict = nagMistake, "Mistake"
+			player.Mistakes++
+		case opts.Inaccuracy > 0 && loss >= opts.Inaccuracy:
+			nag, verdict = nagInaccuracy, "Inaccuracy"
+			player.Inaccuracies++
+		default:
+			continue
+		}
+
+		position := positions[i]
+		annotation := &review.Game.Annotations[i]
+		annotation.NAGs = append(append([]int(nil), annotation.NAGs...), nag)
+		annotation.Variations = append(append([][]move(nil), annotation.Variations...), lines[i].PV)
+		comment := fmt.Sprintf("%s, %v to %v. %s was best.", verdict, review.Scores[i], review.Scores[i+1], position.san(best))
+		if annotation.Comment != "" {
+			comment = annotation.Comment + " " + comment
+		}
+		annotation.Comment = comment
+	}
+
+	for col, player := range []*PlayerReview{&review.White, &review.Black} {
+		if player.Moves > 0 {
+			player.AverageCentipawnLoss /= float64(player.Moves)
+			player.Accuracy = accuracies[col] / float64(player.Moves)
+		}
+	}
+
+	return review
+}
+
+//past a known win it's all the same, going from a mate in 3 to a mate in 5
+//isn't worth a blunder
+func capScore(s Score) int {
+	if s > knownWin {
+		return int(knownWin)
+	}
+	if s < -knownWin {
+		return -int(knownWin)
+	}
+	return int(s)
+}
+
+//the chances of winning a score gives, from 0 to 100, with the curve lichess
+//fit on its games
+func winPercent(centipawns int) float64 {
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(centipawns)))-1)
+}
+
+//how accurate a move that goes from before to after is, from 0 to 100
+func moveAccuracy(before, after int) float64 {
+	accuracy := 103.1668*math.Exp(-0.04354*(winPercent(before)-winPercent(after))) - 3.1669
+	return math.Max(0, math.Min(100, accuracy))
+}
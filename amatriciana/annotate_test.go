@@ -0,0 +1,98 @@
+package amatriciana
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	games, err := ReadPGN(strings.NewReader("1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultAnnotateOptions()
+	opts.Search = SearchOptions{Depth: 4}
+	review := games[0].Annotate(opts)
+
+	if len(review.Scores) != 8 || len(review.Losses) != 7 {
+		t.Fatalf("expected 8 scores and 7 losses, got %d and %d", len(review.Scores), len(review.Losses))
+	}
+	if review.Scores[7] != mateScore {
+		t.Errorf("white has mated, the last score should be %v and not %v", Score(mateScore), review.Scores[7])
+	}
+
+	//3... Nf6 lets the queen mate
+	nf6 := review.Game.Annotations[5]
+	if len(nf6.NAGs) != 1 || nf6.NAGs[0] != nagBlunder || len(nf6.Variations) != 1 || len(nf6.Variations[0]) == 0 {
+		t.Errorf("3... Nf6 should be a blunder with a variation: %+v", nf6)
+	}
+	if review.Black.Blunders != 1 || review.Black.Moves != 3 {
+		t.Errorf("black should have one blunder in three moves: %+v", review.Black)
+	}
+	if review.Losses[6] != 0 || review.White.Blunders != 0 {
+		t.Errorf("the mate can't lose anything: %v, %+v", review.Losses, review.White)
+	}
+	if review.White.Accuracy <= review.Black.Accuracy {
+		t.Errorf("white should be more accurate than black: %+v, %+v", review.White, review.Black)
+	}
+
+	var out strings.Builder
+	if err := review.Game.WritePGN(&out); err != nil {
+		t.Fatal(err)
+	}
+	pgn := out.String()
+	if !strings.Contains(pgn, "3. Bc4 Nf6 $4 {Blunder") || !strings.Contains(pgn, "(3... ") || !strings.Contains(pgn, "4. Qxf7#") {
+		t.Errorf("the annotations aren't in the pgn:\n%s", pgn)
+	}
+
+	//annotations get skipped when it's read back
+	again, err := ReadPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || len(again[0].Moves) != 7 {
+		t.Errorf("the annotated pgn doesn't read back:\n%s", pgn)
+	}
+}
+
+func TestAnnotateKeepsAnnotations(t *testing.T) {
+	games, err := ReadPGN(strings.NewReader("1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7# 1-0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//shorter than the moves, the rest gets filled in
+	game := games[0]
+	game.Annotations = make([]Annotation, 6)
+	game.Annotations[0].Comment = "the king's pawn"
+	game.Annotations[5] = Annotation{NAGs: []int{1}, Comment: "played in a hurry"}
+
+	opts := DefaultAnnotateOptions()
+	opts.Search = SearchOptions{Depth: 4}
+	review := game.Annotate(opts)
+
+	if len(review.Game.Annotations) != 7 || review.Game.Annotations[0].Comment != "the king's pawn" {
+		t.Errorf("the comment on 1. e4 got lost: %+v", review.Game.Annotations)
+	}
+	nf6 := review.Game.Annotations[5]
+	if len(nf6.NAGs) != 2 || nf6.NAGs[0] != 1 || nf6.NAGs[1] != nagBlunder ||
+		!strings.HasPrefix(nf6.Comment, "played in a hurry Blunder") || len(nf6.Variations) != 1 {
+		t.Errorf("the review should add to what 3... Nf6 had: %+v", nf6)
+	}
+	if len(game.Annotations[5].NAGs) != 1 || game.Annotations[5].Comment != "played in a hurry" {
+		t.Errorf("the original game changed: %+v", game.Annotations[5])
+	}
+}
+
+func TestMoveAccuracy(t *testing.T) {
+	if accuracy := moveAccuracy(30, 30); accuracy < 99.9 {
+		t.Errorf("a move that loses nothing should be 100%% accurate, not %.1f", accuracy)
+	}
+	if small, big := moveAccuracy(0, -50), moveAccuracy(0, -300); small <= big {
+		t.Errorf("losing more should be less accurate: %.1f, %.1f", small, big)
+	}
+	if accuracy := moveAccuracy(int(knownWin), -int(knownWin)); accuracy > 1 {
+		t.Errorf("throwing away a won game should be about 0%% accurate, not %.1f", accuracy)
+	}
+}
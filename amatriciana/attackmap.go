@@ -0,0 +1,122 @@
+package amatriciana
+
+//attack maps for the evaluation: every square every piece attacks, worked out
+//once per position instead of generating moves over and over. they're
+//bitboards, one bit per square from a1 (bit 0) to h8 (bit 63)
+
+type attackMap struct {
+	occupied uint64
+	byColor  [2]uint64
+	//the pieces of every type, by color
+	pieces [2][6]uint64
+	//what every piece attacks, in the same order as the board's pieces
+	byPiece []uint64
+	//the squares attacked by pieces of a type, by color
+	byType [2][6]uint64
+	all    [2]uint64
+	//the squares attacked more than once
+	double [2]uint64
+}
+
+var (
+	knightAttacks [64]uint64
+	kingAttacks   [64]uint64
+	//by color
+	pawnAttacks [2][64]uint64
+)
+
+var (
+	rookDirections   = []xy{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = []xy{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+func init() {
+	knightJumps := []xy{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+
+	for square := 0; square < 64; square++ {
+		from := squareXY(square)
+
+		for _, jump := range knightJumps {
+			if to := from.plus(jump); isInBounds(to) {
+				knightAttacks[square] |= to.bit()
+			}
+		}
+
+		for _, to := range kingSquares(square) {
+			kingAttacks[square] |= 1 << uint(to)
+		}
+
+		for _, dx := range []int{-1, 1} {
+			if to := from.plus(xy{dx, 1}); isInBounds(to) {
+				pawnAttacks[0][square] |= to.bit()
+			}
+			if to := from.plus(xy{dx, -1}); isInBounds(to) {
+				pawnAttacks[1][square] |= to.bit()
+			}
+		}
+	}
+}
+
+//the other way around from index()
+func squareXY(square int) xy {
+	return xy{square%8 + 1, square/8 + 1}
+}
+
+func (a xy) bit() uint64 {
+	return 1 << uint(a.index())
+}
+
+//the squares a slider on square attacks, up to and including the first piece it meets
+func slidingAttacks(square int, directions []xy, occupied uint64) uint64 {
+	var attacks uint64
+	from := squareXY(square)
+
+	for _, direction := range directions {
+		for to := from.plus(direction); isInBounds(to); to = to.plus(direction) {
+			attacks |= to.bit()
+			if occupied&to.bit() != 0 {
+				break
+			}
+		}
+	}
+
+	return attacks
+}
+
+func pieceAttacks(p piece, occupied uint64) uint64 {
+	square := p.position.index()
+
+	switch p.pieceType {
+	case pawn:
+		return pawnAttacks[p.color.index()][square]
+	case knight:
+		return knightAttacks[square]
+	case bishop:
+		return slidingAttacks(square, bishopDirections, occupied)
+	case rook:
+		return slidingAttacks(square, rookDirections, occupied)
+	case queen:
+		return slidingAttacks(square, bishopDirections, occupied) | slidingAttacks(square, rookDirections, occupied)
+	case king:
+		return kingAttacks[square]
+	}
+
+	return 0
+}
+
+func (b Board) attackMap() attackMap {
+	m := b.bitboards()
+
+	m.byPiece = make([]uint64, len(b.pieces))
+	for i, p := range b.pieces {
+		col := p.color.index()
+		attacks := pieceAttacks(p, m.occupied)
+
+		m.byPiece[i] = attacks
+		m.byType[col][p.pieceType] |= attacks
+		m.double[col] |= m.all[col] & attacks
+		m.all[col] |= attacks
+	}
+
+	return m
+}
@@ -0,0 +1,229 @@
+package amatriciana
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+//who attacks what: attackers of a square, checkers, pins, discovered
+//checks and x-rays, worked out with the bitboards of attackmap.go
+
+//Square is a square of the board, from a1 (0) to h8 (63)
+type Square int
+
+//ParseSquare reads a square like e4
+func ParseSquare(s string) (Square, error) {
+	a, err := parsexy(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(s) != 2 {
+		return 0, fmt.Errorf("%q isn't a square", s)
+	}
+	return Square(a.index()), nil
+}
+
+func (s Square) String() string {
+	return squareXY(int(s)).String()
+}
+
+func (s Square) bit() uint64 {
+	return 1 << uint(s)
+}
+
+//SquareSet is a set of squares, with one bit for every square like Square
+type SquareSet uint64
+
+//Has tells you if the square is in the set
+func (s SquareSet) Has(square Square) bool {
+	return uint64(s)&square.bit() != 0
+}
+
+//Count is how many squares are in the set
+func (s SquareSet) Count() int {
+	return bits.OnesCount64(uint64(s))
+}
+
+//Squares are the squares in the set, from a1 to h8
+func (s SquareSet) Squares() []Square {
+	squares := make([]Square, 0, s.Count())
+	for set := uint64(s); set != 0; set &= set - 1 {
+		squares = append(squares, Square(bits.TrailingZeros64(set)))
+	}
+	return squares
+}
+
+func (s SquareSet) String() string {
+	names := make([]string, 0, s.Count())
+	for _, square := range s.Squares() {
+		names = append(names, square.String())
+	}
+	return strings.Join(names, " ")
+}
+
+//Color is white or black
+type Color = color
+
+//the colors, for the functions that want one
+const (
+	White Color = white
+	Black Color = black
+)
+
+//Pin is a piece that can't leave the line between its king and the enemy
+//slider behind it without leaving the king in check
+type Pin struct {
+	Pinned Square
+	Pinner Square
+	//Ray is where the pinned piece can still go: the squares between the
+	//king and the pinner, and the pinner itself
+	Ray SquareSet
+}
+
+//XRay is a slider that would attack a square if the piece in front of it moved away
+type XRay struct {
+	Attacker Square
+	Blocker  Square
+}
+
+var (
+	//the squares strictly between two squares on the same line, 0 if they aren't on one
+	between [64][64]uint64
+	//what a rook and a bishop would attack from a square on an empty board
+	rookRays   [64]uint64
+	bishopRays [64]uint64
+)
+
+func init() {
+	for square := 0; square < 64; square++ {
+		rookRays[square] = slidingAttacks(square, rookDirections, 0)
+		bishopRays[square] = slidingAttacks(square, bishopDirections, 0)
+	}
+
+	for from := 0; from < 64; from++ {
+		for _, direction := range append(append([]xy{}, rookDirections...), bishopDirections...) {
+			var path uint64
+			for to := squareXY(from).plus(direction); isInBounds(to); to = to.plus(direction) {
+				between[from][to.index()] = path
+				path |= to.bit()
+			}
+		}
+	}
+
+}
+
+//the pieces of the board as bitboards, without the attacks
+func (b Board) bitboards() attackMap {
+	var m attackMap
+	for _, p := range b.pieces {
+		bit := p.position.bit()
+		m.occupied |= bit
+		m.byColor[p.color.index()] |= bit
+		m.pieces[p.color.index()][p.pieceType] |= bit
+	}
+	return m
+}
+
+//the pieces of col that attack square when only the pieces in occupied are
+//on the board. leaving a piece out of occupied lets the sliders see through it
+func (m attackMap) attackersOf(square int, col color, occupied uint64) uint64 {
+	pieces := &m.pieces[col.index()]
+
+	attackers := pawnAttacks[(!col).index()][square] & pieces[pawn]
+	attackers |= knightAttacks[square] & pieces[knight]
+	attackers |= kingAttacks[square] & pieces[king]
+	if straight := pieces[rook] | pieces[queen]; straight&rookRays[square] != 0 {
+		attackers |= slidingAttacks(square, rookDirections, occupied) & straight
+	}
+	if diagonal := pieces[bishop] | pieces[queen]; diagonal&bishopRays[square] != 0 {
+		attackers |= slidingAttacks(square, bishopDirections, occupied) & diagonal
+	}
+
+	return attackers & occupied
+}
+
+//the sliders of col that are lined up with square with exactly one piece
+//in between, and that piece. it's the same thing for pins, discovered
+//checks and x-rays, the only difference is whose pieces they are
+func (m attackMap) blockers(square int, col color) []XRay {
+	pieces := &m.pieces[col.index()]
+	sliders := (rookRays[square] & (pieces[rook] | pieces[queen])) |
+		(bishopRays[square] & (pieces[bishop] | pieces[queen]))
+
+	var found []XRay
+	for ; sliders != 0; sliders &= sliders - 1 {
+		slider := bits.TrailingZeros64(sliders)
+		inBetween := between[square][slider] & m.occupied
+		if bits.OnesCount64(inBetween) == 1 {
+			found = append(found, XRay{Square(slider), Square(bits.TrailingZeros64(inBetween))})
+		}
+	}
+	return found
+}
+
+func (m attackMap) kingSquare(col color) (int, bool) {
+	kings := m.pieces[col.index()][king]
+	return bits.TrailingZeros64(kings), kings != 0
+}
+
+//Attackers are the pieces of col that attack square
+func (b Board) Attackers(square Square, col Color) SquareSet {
+	m := b.bitboards()
+	return SquareSet(m.attackersOf(int(square), col, m.occupied))
+}
+
+//AttackedSquares are all the squares col attacks, with its own pieces on them too
+func (b Board) AttackedSquares(col Color) SquareSet {
+	return SquareSet(b.attackMap().all[col.index()])
+}
+
+//Checkers are the pieces giving check to the side to move
+func (b Board) Checkers() SquareSet {
+	m := b.bitboards()
+	king, found := m.kingSquare(b.turn)
+	if !found {
+		return 0
+	}
+	return SquareSet(m.attackersOf(king, !b.turn, m.occupied))
+}
+
+//Pins are the pieces of col pinned to their king
+func (b Board) Pins(col Color) []Pin {
+	m := b.bitboards()
+	king, found := m.kingSquare(col)
+	if !found {
+		return nil
+	}
+
+	var pins []Pin
+	for _, x := range m.blockers(king, !col) {
+		if m.byColor[col.index()]&x.Blocker.bit() != 0 {
+			ray := between[king][x.Attacker] | x.Attacker.bit()
+			pins = append(pins, Pin{x.Blocker, x.Attacker, SquareSet(ray)})
+		}
+	}
+	return pins
+}
+
+//DiscoveredCheckCandidates are the pieces of col that give check with the
+//slider behind them as soon as they get off the line to the enemy king
+func (b Board) DiscoveredCheckCandidates(col Color) SquareSet {
+	m := b.bitboards()
+	king, found := m.kingSquare(!col)
+	if !found {
+		return 0
+	}
+
+	var candidates uint64
+	for _, x := range m.blockers(king, col) {
+		candidates |= m.byColor[col.index()] & x.Blocker.bit()
+	}
+	return SquareSet(candidates)
+}
+
+//XRays are the sliders of col that would attack square if the piece in
+//front of them, of either color, weren't there
+func (b Board) XRays(square Square, col Color) []XRay {
+	return b.bitboards().blockers(int(square), col)
+}
@@ -0,0 +1,139 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func boardOrFail(t *testing.T, fen string) Board {
+	board, err := BoardFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+func squareOrFail(t *testing.T, s string) Square {
+	square, err := ParseSquare(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return square
+}
+
+func TestParseSquare(t *testing.T) {
+	for _, s := range []string{"a1", "e4", "h8"} {
+		if square := squareOrFail(t, s); square.String() != s {
+			t.Errorf("%s came back as %s", s, square)
+		}
+	}
+	if square := squareOrFail(t, "h8"); square != 63 {
+		t.Errorf("h8 should be 63, not %d", square)
+	}
+	for _, s := range []string{"", "e", "e9", "i1", "e44"} {
+		if _, err := ParseSquare(s); err == nil {
+			t.Errorf("%q shouldn't be a square", s)
+		}
+	}
+}
+
+func TestAttackersOfSquare(t *testing.T) {
+	board := boardOrFail(t, "k7/4r3/5n2/3p4/8/8/8/K7 w - - 0 1")
+
+	if attackers := board.Attackers(squareOrFail(t, "e4"), Black); attackers.String() != "d5 f6 e7" {
+		t.Errorf("wrong attackers of e4: %v", attackers)
+	}
+	if attackers := board.Attackers(squareOrFail(t, "e4"), White); attackers != 0 {
+		t.Errorf("white doesn't attack e4: %v", attackers)
+	}
+	//the pawn is in the way of the queen
+	board = boardOrFail(t, "k7/8/8/3p4/8/8/8/K2q4 w - - 0 1")
+	if attackers := board.Attackers(squareOrFail(t, "d8"), Black); attackers != 0 {
+		t.Errorf("the queen doesn't see through the pawn: %v", attackers)
+	}
+
+	board = boardOrFail(t, "k7/4r3/5n2/3p4/8/8/8/K7 w - - 0 1")
+	attacked := board.AttackedSquares(Black)
+	for _, s := range []string{"c4", "e4", "e8", "a7", "b8"} {
+		if !attacked.Has(squareOrFail(t, s)) {
+			t.Errorf("black attacks %s", s)
+		}
+	}
+	if attacked.Has(squareOrFail(t, "d4")) {
+		t.Error("black doesn't attack d4")
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	tests := []struct {
+		fen, checkers string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ""},
+		{"4k3/8/8/8/8/5n2/8/r3K3 w - - 0 1", "a1 f3"},
+		{"4k3/8/8/8/8/8/3p4/4K3 w - - 0 1", "d2"},
+		{"4k3/8/8/8/8/8/8/r2NK3 w - - 0 1", ""},
+	}
+
+	for _, test := range tests {
+		board := boardOrFail(t, test.fen)
+		if checkers := board.Checkers(); checkers.String() != test.checkers {
+			t.Errorf("%s: checkers should be %q, not %q", test.fen, test.checkers, checkers)
+		}
+		if board.isKingInCheck(board.turn) != (test.checkers != "") {
+			t.Errorf("%s: isKingInCheck doesn't agree", test.fen)
+		}
+	}
+}
+
+func TestPins(t *testing.T) {
+	board := boardOrFail(t, "4k3/4r3/8/8/1b6/8/3PB3/4K3 w - - 0 1")
+	pins := board.Pins(White)
+	if len(pins) != 2 {
+		t.Fatalf("there should be two pins: %v", pins)
+	}
+	for _, pin := range pins {
+		switch pin.Pinned.String() {
+		case "d2":
+			if pin.Pinner.String() != "b4" || pin.Ray.String() != "d2 c3 b4" {
+				t.Errorf("wrong pin of d2: %v %v", pin.Pinner, pin.Ray)
+			}
+		case "e2":
+			if pin.Pinner.String() != "e7" || pin.Ray.String() != "e2 e3 e4 e5 e6 e7" {
+				t.Errorf("wrong pin of e2: %v %v", pin.Pinner, pin.Ray)
+			}
+		default:
+			t.Errorf("%v isn't pinned", pin.Pinned)
+		}
+	}
+
+	//two pieces in the way aren't a pin, and neither is a piece of the pinner's color
+	board = boardOrFail(t, "4k3/4r3/8/4p3/1b6/2p5/4B3/4K3 w - - 0 1")
+	if pins := board.Pins(White); len(pins) != 0 {
+		t.Errorf("there shouldn't be any pins: %v", pins)
+	}
+	if pins := board.Pins(Black); len(pins) != 0 {
+		t.Errorf("black has nothing pinned: %v", pins)
+	}
+}
+
+func TestDiscoveredCheckCandidates(t *testing.T) {
+	//the pawn in front of the bishop is black's
+	board := boardOrFail(t, "4k3/3p4/8/8/B7/8/4N3/4R1K1 w - - 0 1")
+	if candidates := board.DiscoveredCheckCandidates(White); candidates.String() != "e2" {
+		t.Errorf("wrong discovered check candidates: %v", candidates)
+	}
+	if candidates := board.DiscoveredCheckCandidates(Black); candidates != 0 {
+		t.Errorf("black doesn't have any: %v", candidates)
+	}
+}
+
+func TestXRays(t *testing.T) {
+	board := boardOrFail(t, "4k3/8/8/8/8/8/4R3/4Q1K1 w - - 0 1")
+	xrays := board.XRays(squareOrFail(t, "e7"), White)
+	if len(xrays) != 1 || xrays[0].Attacker.String() != "e1" || xrays[0].Blocker.String() != "e2" {
+		t.Errorf("the queen should x-ray e7 through the rook: %v", xrays)
+	}
+	//the rook attacks e7 straight away, that isn't an x-ray
+	if xrays := board.XRays(squareOrFail(t, "e8"), White); len(xrays) != 1 {
+		t.Errorf("only the queen x-rays e8: %v", xrays)
+	}
+}
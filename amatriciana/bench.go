@@ -0,0 +1,81 @@
+package amatriciana
+
+import (
+	"time"
+)
+
+//the positions of the bench: openings, middlegames full of tactics and
+//endgames, so that every part of the search and the evaluation gets used
+var benchPositions = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 10",
+	"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+	"rnbq1rk1/ppp1bppp/4pn2/3p2B1/2PP4/2N2N2/PP2PPPP/R2QKB1R w KQ - 4 6",
+	"r1bq1rk1/pp2nppp/2n1p3/3pP3/2pP4/P1P2N2/2P2PPP/R1BQKB1R w KQ - 1 9",
+	"2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1",
+	"r1b2rk1/2q1b1pp/p2ppn2/1p6/3QP3/1BN1B3/PPP3PP/R4RK1 w - - 0 14",
+	"4rrk1/pp1n3p/3q2pQ/2p1pb2/2PP4/2P3N1/P2B2PP/4RRK1 b - - 7 19",
+	"r3r1k1/2p2ppp/p1p1bn2/8/1q2P3/2NPQN2/PPP3PP/R4RK1 b - - 2 15",
+	"6k1/6p1/6Pp/ppp5/3pn2P/1P3K2/1PP2P2/3N4 b - - 0 1",
+	"3b4/5kp1/1p1p1p1p/pP1PpP1P/P1P1P3/3KN3/8/8 w - - 0 1",
+	"8/8/3k4/3p4/3P4/3K4/8/8 w - - 0 1",
+	"8/3k4/8/8/8/4B3/4KB2/2B5 w - - 0 1",
+	"5k2/7R/4P2p/5K2/p1r2P1p/8/8/8 b - - 0 1",
+	"8/6pk/1p6/8/PP3p1p/5P2/4KP1q/3Q4 w - - 0 1",
+	"7k/3p2pp/4q3/8/4Q3/5Kp1/P6b/8 w - - 0 1",
+}
+
+//the depth of the bench when nobody says otherwise
+const defaultBenchDepth = 4
+
+//BenchResult is what the bench counted
+type BenchResult struct {
+	Positions int
+	Nodes     int
+	Time      time.Duration
+}
+
+//NPS is how many nodes were searched every second
+func (r BenchResult) NPS() int {
+	if r.Time <= 0 {
+		return 0
+	}
+	return int(float64(r.Nodes) / r.Time.Seconds())
+}
+
+//Bench searches a fixed set of positions to depth (0 means 4) with evaluator
+//(nil means the default weights), one thread and an empty table every time.
+//the nodes are always the same for the same engine, so when a change makes
+//them different it changed what the search does. each gets called after
+//every position
+func Bench(depth int, evaluator *Evaluator, each func(fen string, nodes int, elapsed time.Duration)) BenchResult {
+	if depth <= 0 {
+		depth = defaultBenchDepth
+	}
+
+	table := NewTranspositionTable(defaultHashSize)
+	var result BenchResult
+	for _, fen := range benchPositions {
+		b, err := BoardFromFEN(fen)
+		if err != nil {
+			panic("the bench position " + fen + " is broken: " + err.Error())
+		}
+
+		table.Clear()
+		nodes := 0
+		start := time.Now()
+		b.Search(SearchOptions{Depth: depth, Table: table, Evaluator: evaluator, Info: func(info SearchInfo) {
+			nodes = info.Nodes
+		}})
+		elapsed := time.Since(start)
+
+		result.Positions++
+		result.Nodes += nodes
+		result.Time += elapsed
+		if each != nil {
+			each(fen, nodes, elapsed)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,17 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestBenchIsDeterministic(t *testing.T) {
+	first := Bench(2, nil, nil)
+	second := Bench(2, nil, nil)
+
+	if first.Positions != len(benchPositions) || first.Nodes == 0 {
+		t.Fatalf("unexpected bench %+v", first)
+	}
+	if first.Nodes != second.Nodes {
+		t.Errorf("the same bench searched %d nodes and then %d", first.Nodes, second.Nodes)
+	}
+}
@@ -29,6 +29,12 @@ type Board struct {
 	enPassant      xy
 	moveNumber     int
 	halfMoves      int
+	//kept up to date by move(), so evaluating doesn't go through every piece.
+	//the piece-square score is by color
+	psq   [2]taperedScore
+	phase int
+	//only there when a network evaluates the board, the search sets it up
+	nnue *accumulator
 }
 
 func (b Board) Turn() string {
@@ -45,16 +51,6 @@ func (b Board) pieceAtPosition(pos xy) (piece, bool) {
 	return piece{}, false
 }
 
-func (b Board) pointerPieceAtPosition(pos xy) (*piece, bool) {
-	for _, p := range b.pieces {
-		if p.position == pos {
-			return &p, true
-		}
-	}
-
-	return nil, false
-}
-
 func (b Board) piecesOfColor(col color) []piece {
 	pieces := make([]piece, 0)
 
@@ -67,20 +63,9 @@ func (b Board) piecesOfColor(col color) []piece {
 	return pieces
 }
 
+//moves are the legal moves of col, see movegen.go
 func (b Board) moves(col color) []move {
-	pieces := b.piecesOfColor(col)
-	moves := make([]move, 0)
-
-	for _, piece := range pieces {
-		pieceMoves := piece.moves(b)
-		for _, move := range pieceMoves {
-			if b.isLegal(move) {
-				moves = append(moves, move)
-			}
-		}
-	}
-
-	return moves
+	return b.legalMoves(col, allMoves)
 }
 
 //IsCheckmate tells you if the current player to move is in checkmate
@@ -89,75 +74,28 @@ func (b Board) IsCheckmate() bool {
 		return false
 	}
 
-	moves := b.moves(b.turn)
-
-	if len(moves) == 1 {
-		return true
-	}
-
-	for _, move := range moves {
-		dummyBoard := b.Clone()
-		dummyBoard.move(move)
+	//moves() already throws away everything that leaves the king in check
+	return len(b.moves(b.turn)) == 0
+}
 
-		if !dummyBoard.isKingInCheck(b.turn) {
-			return false
-		}
+//IsStalemate tells you if the current player to move has no legal moves
+//but isn't in check
+func (b Board) IsStalemate() bool {
+	if b.isKingInCheck(b.turn) {
+		return false
 	}
 
-	return true
+	return len(b.moves(b.turn)) == 0
 }
 
+//tells you if the other side attacks square, whatever is standing there
 func (b Board) isSquareInCheck(square xy, col color) bool {
-	diagonals := [...]xy{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
-	for _, direction := range diagonals {
-		piece, something := b.pieceAtPosition(b.lastSquareInDirection(square, direction, col))
-		if something && piece.color != col {
-			if piece.pieceType == queen || piece.pieceType == bishop {
-				return true
-			}
-		}
-	}
-
-	straightLines := [...]xy{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
-	for _, direction := range straightLines {
-		piece, something := b.pieceAtPosition(b.lastSquareInDirection(square, direction, col))
-		if something && piece.color != col {
-			if piece.pieceType == queen || piece.pieceType == rook {
-				return true
-			}
-		}
-	}
-
-	knightMoves := [...]xy{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {2, -1}, {2, 1}, {1, -2}, {1, 2}}
-	for _, direction := range knightMoves {
-		piece, something := b.pieceAtPosition(square.plus(direction))
-		if something && piece.color != col && piece.pieceType == knight {
-			return true
-		}
-	}
-	//      oooooooo
-	//     o       ooooooooooooooooooooooooooo
-	//     ooooooooo                     o    o
-	//     o       o                     o    o
-	//     o       ooooooooooooooooooooooooooo
-	//     oooooooo
-
-	var otherSide xy
-	if col == white {
-		otherSide = xy{0, 1}
-	} else {
-		otherSide = xy{0, -1}
-	}
-
-	pawnCaptures := [2]xy{xy{1, 0}.plus(otherSide), xy{-1, 0}.plus(otherSide)}
-	for _, direction := range pawnCaptures {
-		piece, something := b.pieceAtPosition(square.plus(direction))
-		if something && piece.color != col && piece.pieceType == pawn {
-			return true
-		}
+	if !isInBounds(square) {
+		return false
 	}
 
-	return false
+	m := b.bitboards()
+	return m.attackersOf(square.index(), !col, m.occupied) != 0
 }
 
 func (b Board) isKingInCheck(col color) bool {
@@ -428,14 +366,19 @@ func BoardFromFEN(fen string) (Board, error) {
 		board.blackCanCastle[0] = true
 	}
 	if strings.Contains(castling, "Q") {
-		board.whiteCanCastle[0] = true
+		board.whiteCanCastle[1] = true
 	}
 	if strings.Contains(castling, "q") {
-		board.blackCanCastle[0] = true
+		board.blackCanCastle[1] = true
 	}
 
-	enPassant := elements[3]
-	_ = enPassant
+	if elements[3] != "-" {
+		enPassant, err := parsexy(elements[3])
+		if err != nil {
+			return board, err
+		}
+		board.enPassant = enPassant
+	}
 
 	halfMoves, err := strconv.Atoi(elements[4])
 	if err != nil {
@@ -443,12 +386,13 @@ func BoardFromFEN(fen string) (Board, error) {
 	}
 	board.halfMoves = halfMoves
 
-	moveNumber, err := strconv.Atoi(elements[4])
+	moveNumber, err := strconv.Atoi(elements[5])
 	if err != nil {
 		return board, err
 	}
 	board.moveNumber = moveNumber
 
+	board.initPSQ()
 	return board, nil
 }
 
@@ -459,6 +403,7 @@ func NewBoard() Board {
 		turn:           white,
 		whiteCanCastle: [2]bool{true, true},
 		blackCanCastle: [2]bool{true, true},
+		moveNumber:     1,
 	}
 
 	board.pieces[0] = piece{xy{1, 1}, white, rook}
@@ -497,5 +442,6 @@ func NewBoard() Board {
 	board.pieces[30] = piece{xy{7, 7}, black, pawn}
 	board.pieces[31] = piece{xy{8, 7}, black, pawn}
 
+	board.initPSQ()
 	return board
 }
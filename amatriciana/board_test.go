@@ -1,6 +1,7 @@
 package amatriciana
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -62,3 +63,91 @@ func TestParseMove(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestPromoteToKing(t *testing.T) {
+	board, err := BoardFromFEN("8/4P3/8/8/8/k7/8/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{"e7e8k", "e7e8p"} {
+		if err := board.PerformMove(input); err == nil {
+			t.Error(input, "shouldn't be allowed")
+		}
+	}
+	if err := board.PerformMove("e7e8q"); err != nil {
+		t.Error("e7e8q should be fine:", err)
+	}
+}
+
+func TestSpecialMoves(t *testing.T) {
+	board, err := BoardFromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = board.PerformMove("e1c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = board.PerformMove("e8g8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(board.FEN(), "r4rk1/8/8/8/8/8/8/2KR3R w - -") {
+		t.Error("castling went wrong:", board.FEN())
+	}
+
+	//queenside rights, the en passant square and the move number
+	board, err = BoardFromFEN("r3k3/8/8/3pP3/8/8/8/4K2R w Kq d6 0 12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.whiteCanCastle != [2]bool{true, false} || board.blackCanCastle != [2]bool{false, true} ||
+		board.enPassant != (xy{4, 6}) || board.moveNumber != 12 {
+		t.Error("the fen fields are wrong:", board.FEN())
+	}
+
+	//a pawn on the last rank can become any of the four pieces
+	board, err = BoardFromFEN("8/P6k/8/8/8/8/8/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves := board.pawnMoves(xy{1, 7}, white); len(moves) != 4 {
+		t.Error("expected 4 promotions, got", len(moves))
+	}
+
+	//the kings can't get next to each other
+	board, err = BoardFromFEN("8/8/8/8/8/3k4/8/3K4 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves := board.moves(white); len(moves) != 2 {
+		t.Error("the king should only have c1 and e1, got", len(moves))
+	}
+
+	board, err = BoardFromFEN("k7/2Q5/1K6/8/8/8/8/8 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !board.IsStalemate() || board.IsCheckmate() {
+		t.Error("black has no moves and isn't in check, it's stalemate")
+	}
+
+	board, err = BoardFromFEN("4k3/8/8/8/1p6/8/P7/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = board.PerformMove("a2a4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = board.PerformMove("b4a3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(board.FEN(), "4k3/8/8/8/8/p7/8/4K3 w - -") {
+		t.Error("en passant went wrong:", board.FEN())
+	}
+}
@@ -0,0 +1,298 @@
+package amatriciana
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+//BookEntry is one record of a polyglot book: a move for the position with
+//that key, and how much the book likes it
+type BookEntry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+//Book is an opening book in the polyglot format
+type Book struct {
+	//sorted by key, like in the file
+	entries []BookEntry
+}
+
+//BookMove is a move the book suggests for a position
+type BookMove struct {
+	Move   move
+	Weight uint16
+}
+
+//BookSelection is how a move gets picked when the book has more than one
+type BookSelection int
+
+const (
+	//BookWeighted picks at random, but moves with more weight come up more often
+	BookWeighted BookSelection = iota
+	//BookBest always picks the move with the most weight
+	BookBest
+	//BookRandom picks any of the moves with the same chance
+	BookRandom
+)
+
+//BookOptions changes the way moves get picked out of the book
+type BookOptions struct {
+	Selection BookSelection
+	//Depth is the last ply the book gets used for. 0 means no limit
+	Depth int
+	//Rand is where random choices come from, nil means the global source
+	Rand *rand.Rand
+}
+
+//LoadBook reads a polyglot book from a file
+func LoadBook(path string) (*Book, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBook(file)
+}
+
+//ReadBook reads a polyglot book: 16 bytes for every entry, all big endian
+func ReadBook(r io.Reader) (*Book, error) {
+	book := &Book{entries: make([]BookEntry, 0)}
+
+	for {
+		var entry BookEntry
+		err := binary.Read(r, binary.BigEndian, &entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("broken book entry %d: %s", len(book.entries), err.Error())
+		}
+
+		book.entries = append(book.entries, entry)
+	}
+
+	//a book that isn't sorted is broken, but it's cheap to fix
+	if !sort.SliceIsSorted(book.entries, func(i, j int) bool { return book.entries[i].Key < book.entries[j].Key }) {
+		sortEntries(book.entries)
+	}
+
+	return book, nil
+}
+
+//Write saves the book in the polyglot format
+func (bk *Book) Write(w io.Writer) error {
+	var buffer bytes.Buffer
+	for _, entry := range bk.entries {
+		binary.Write(&buffer, binary.BigEndian, entry)
+	}
+
+	_, err := buffer.WriteTo(w)
+	return err
+}
+
+//Len is how many entries the book has
+func (bk *Book) Len() int {
+	return len(bk.entries)
+}
+
+//Moves gives back the legal moves the book has for the position, most weight first
+func (bk *Book) Moves(b Board) []BookMove {
+	key := b.PolyglotKey()
+	moves := make([]BookMove, 0)
+
+	first := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].Key >= key })
+	legalMoves := b.moves(b.turn)
+
+	for i := first; i < len(bk.entries) && bk.entries[i].Key == key; i++ {
+		for _, m := range legalMoves {
+			if m.polyglot() == bk.entries[i].Move {
+				moves = append(moves, BookMove{m, bk.entries[i].Weight})
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Weight > moves[j].Weight })
+	return moves
+}
+
+//PickMove chooses a move out of the book, if the book knows the position
+func (bk *Book) PickMove(b Board, opts BookOptions) (move, bool) {
+	if opts.Depth > 0 && b.ply() >= opts.Depth {
+		return move{}, false
+	}
+
+	moves := bk.Moves(b)
+	if len(moves) == 0 {
+		return move{}, false
+	}
+
+	random := rand.Intn
+	if opts.Rand != nil {
+		random = opts.Rand.Intn
+	}
+
+	switch opts.Selection {
+	case BookBest:
+		return moves[0].Move, true
+	case BookRandom:
+		return moves[random(len(moves))].Move, true
+	}
+
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+
+	//every move has weight 0, so no move is better than the others
+	if total == 0 {
+		return moves[random(len(moves))].Move, true
+	}
+
+	pick := random(total)
+	for _, m := range moves {
+		pick -= int(m.Weight)
+		if pick < 0 {
+			return m.Move, true
+		}
+	}
+
+	return moves[0].Move, true
+}
+
+//how many plies have been played since the start of the game
+func (b Board) ply() int {
+	ply := (b.moveNumber - 1) * 2
+	if b.turn == black {
+		ply++
+	}
+	return ply
+}
+
+//polyglot moves are from and to in the low 12 bits and the promotion above,
+//and castling is written as the king taking its own rook
+func (m move) polyglot() uint16 {
+	to := m.to
+	if m.moveType == shortCastle {
+		to = xy{8, m.from.y}
+	} else if m.moveType == longCastle {
+		to = xy{1, m.from.y}
+	}
+
+	var promotesInto uint16
+	if m.moveType == promotion {
+		//knight is 1, bishop 2, rook 3, queen 4
+		promotesInto = uint16(m.promotesInto)
+	}
+
+	return uint16(to.x-1) | uint16(to.y-1)<<3 | uint16(m.from.x-1)<<6 | uint16(m.from.y-1)<<9 | promotesInto<<12
+}
+
+//sorted by key, and the moves of the same position by weight
+func sortEntries(entries []BookEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Key != entries[j].Key {
+			return entries[i].Key < entries[j].Key
+		}
+		if entries[i].Weight != entries[j].Weight {
+			return entries[i].Weight > entries[j].Weight
+		}
+		return entries[i].Move < entries[j].Move
+	})
+}
+
+//BookBuildOptions filters what goes into a book made out of games
+type BookBuildOptions struct {
+	//MaxPly is how far into each game moves get added. 0 means the whole game
+	MaxPly int
+	//MinGames is how many times a move has to be played to make it in
+	MinGames int
+	//MinScore is the score the side that played the move needs to have
+	//made with it, from 0 to 1 with draws counting half
+	MinScore float64
+}
+
+type bookStats struct {
+	games, wins, draws int
+}
+
+//BuildBook makes a polyglot book out of games. every move gets as weight
+//two points for each win and one for each draw it got
+func BuildBook(games []Game, opts BookBuildOptions) *Book {
+	stats := make(map[uint64]map[uint16]*bookStats)
+
+	for _, game := range games {
+		//unfinished games don't say anything about how good a move is
+		if game.Result != "1-0" && game.Result != "0-1" && game.Result != "1/2-1/2" {
+			continue
+		}
+
+		board := game.Start.Clone()
+		for ply, m := range game.Moves {
+			if opts.MaxPly > 0 && ply >= opts.MaxPly {
+				break
+			}
+
+			key := board.PolyglotKey()
+			if stats[key] == nil {
+				stats[key] = make(map[uint16]*bookStats)
+			}
+			moveStats := stats[key][m.polyglot()]
+			if moveStats == nil {
+				moveStats = &bookStats{}
+				stats[key][m.polyglot()] = moveStats
+			}
+
+			moveStats.games++
+			switch {
+			case game.Result == "1/2-1/2":
+				moveStats.draws++
+			case (game.Result == "1-0") == (board.turn == white):
+				moveStats.wins++
+			}
+
+			board.move(m)
+		}
+	}
+
+	book := &Book{entries: make([]BookEntry, 0, len(stats))}
+	for key, moves := range stats {
+		//weights are only 16 bits, so the position's moves get scaled down together
+		maxPoints := 0
+		for _, s := range moves {
+			if points := 2*s.wins + s.draws; points > maxPoints {
+				maxPoints = points
+			}
+		}
+		scale := 1.0
+		if maxPoints > 0xffff {
+			scale = float64(0xffff) / float64(maxPoints)
+		}
+
+		for packed, s := range moves {
+			if s.games < opts.MinGames {
+				continue
+			}
+
+			score := (float64(s.wins) + float64(s.draws)/2) / float64(s.games)
+			if score < opts.MinScore {
+				continue
+			}
+
+			weight := uint16(float64(2*s.wins+s.draws) * scale)
+			book.entries = append(book.entries, BookEntry{Key: key, Move: packed, Weight: weight})
+		}
+	}
+
+	sortEntries(book.entries)
+	return book
+}
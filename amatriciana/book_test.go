@@ -0,0 +1,80 @@
+package amatriciana
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestBuildBook(t *testing.T) {
+	pgn := strings.Repeat("1. e4 e5 2. Nf3 Nc6 1-0\n\n", 3) +
+		strings.Repeat("1. d4 d5 0-1\n\n", 2) +
+		"1. c4 e5 1/2-1/2\n"
+
+	games, err := ReadPGN(strings.NewReader(pgn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := BuildBook(games, BookBuildOptions{MinGames: 2, MinScore: 0.5})
+
+	moves := book.Moves(NewBoard())
+	if len(moves) != 1 || moves[0].Move.UCIString() != "e2e4" || moves[0].Weight != 6 {
+		t.Fatal("only 1. e4 won often enough, got", moves)
+	}
+
+	afterD4 := NewBoard()
+	afterD4.PerformMove("d2d4")
+	if len(book.Moves(afterD4)) != 1 {
+		t.Error("1... d5 won both its games, it should be in the book")
+	}
+
+	var file bytes.Buffer
+	err = book.Write(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Len() != 16*book.Len() {
+		t.Error("every entry should take 16 bytes")
+	}
+
+	reread, err := ReadBook(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reread.Len() != book.Len() {
+		t.Error("the book changed while saving it")
+	}
+
+	opts := BookOptions{Selection: BookBest, Rand: rand.New(rand.NewSource(1))}
+	picked, found := reread.PickMove(NewBoard(), opts)
+	if !found || picked.UCIString() != "e2e4" {
+		t.Error("expected 1. e4 out of the book")
+	}
+
+	opts.Depth = 2
+	board := NewBoard()
+	board.PerformMove("e2e4")
+	board.PerformMove("e7e5")
+	if _, found := reread.PickMove(board, opts); found {
+		t.Error("the book should stop after two plies")
+	}
+}
+
+func TestPolyglotCastling(t *testing.T) {
+	board, err := BoardFromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	castle, err := board.parseMove("e1g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//e1h1
+	if castle.polyglot() != 7|4<<6 {
+		t.Errorf("castling should be written as e1h1, got %x", castle.polyglot())
+	}
+}
@@ -0,0 +1,339 @@
+package amatriciana
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//training data for the network: the engine plays itself at a fixed number
+//of nodes per move from a few random moves in, and every quiet position it
+//goes through gets saved with the score the search gave it and the result
+
+//TrainingPosition is a position from a self-play game with the search's score
+//and the result of the game, both from white's point of view. the result is
+//1 if white won, 0.5 for a draw and 0 if black won
+type TrainingPosition struct {
+	Board  Board
+	Score  Score
+	Result float64
+}
+
+//DatagenOptions says how the games get played
+type DatagenOptions struct {
+	//Nodes is how many nodes every move gets. 0 means 5000
+	Nodes int
+	//RandomPlies is how many random moves every game starts with, so
+	//that no two games are the same. 0 means 8
+	RandomPlies int
+	//MaxPlies is how long a game can go on before it's called a draw. 0 means 400
+	MaxPlies int
+	//Evaluator scores the positions, nil means the default weights
+	Evaluator *Evaluator
+	//Table gets cleared before every game, nil means a new one every game
+	Table *TranspositionTable
+	//Random picks the opening moves, nil means one seeded with the time
+	Random *rand.Rand
+}
+
+const (
+	defaultDatagenNodes = 5000
+	defaultRandomPlies  = 8
+	defaultMaxPlies     = 400
+)
+
+//PlayTrainingGame plays a game against itself and gives back the positions
+//worth training on and the result, like 1-0. positions in check, positions
+//where the best move is a capture or a promotion and mate scores are left
+//out, they say more about the tactics than about the position
+func PlayTrainingGame(opts DatagenOptions) ([]TrainingPosition, string) {
+	if opts.Nodes <= 0 {
+		opts.Nodes = defaultDatagenNodes
+	}
+	if opts.RandomPlies <= 0 {
+		opts.RandomPlies = defaultRandomPlies
+	}
+	if opts.MaxPlies <= 0 {
+		opts.MaxPlies = defaultMaxPlies
+	}
+	if opts.Table == nil {
+		opts.Table = NewTranspositionTable(defaultHashSize)
+	}
+	if opts.Random == nil {
+		opts.Random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	opts.Table.Clear()
+
+	b := RandomOpening(opts.Random, opts.RandomPlies).Position()
+	seen := map[uint64]int{b.Hash(): 1}
+
+	var positions []TrainingPosition
+	var result string
+	adjudication := adjudicator{Adjudication: DefaultAdjudication()}
+
+	for ply := 0; ; ply++ {
+		var over bool
+		if result, over = b.gameResult(seen); over {
+			break
+		}
+		if result, over = adjudication.tablebase(b); over {
+			break
+		}
+		if ply >= opts.MaxPlies {
+			result = "1/2-1/2"
+			break
+		}
+
+		lines := b.Search(SearchOptions{Nodes: opts.Nodes, Table: opts.Table, Evaluator: opts.Evaluator})
+		best := lines[0]
+		score := best.Score
+		if b.turn == black {
+			score = -score
+		}
+
+		if !b.isKingInCheck(b.turn) && !b.isCapture(best.Move) && best.Move.moveType != promotion && !score.IsMate() {
+			positions = append(positions, TrainingPosition{Board: b.clonePosition(), Score: score})
+		}
+
+		if result, over = adjudication.update(ply, score); over {
+			break
+		}
+
+		b.move(best.Move)
+		seen[b.Hash()]++
+	}
+
+	value := resultValue(result)
+	for i := range positions {
+		positions[i].Result = value
+	}
+	return positions, result
+}
+
+//RandomOpening plays plies random legal moves from the usual starting
+//position, and starts over if the game ends before
+func RandomOpening(random *rand.Rand, plies int) Game {
+	for {
+		game := newGame()
+		b := game.Start.Clone()
+		for i := 0; i < plies; i++ {
+			moves := b.moves(b.turn)
+			if len(moves) == 0 {
+				break
+			}
+			m := moves[random.Intn(len(moves))]
+			b.move(m)
+			game.Moves = append(game.Moves, m)
+		}
+
+		if len(b.moves(b.turn)) > 0 {
+			return game
+		}
+	}
+}
+
+//gameResult tells you if the game is over and how it ended. seen counts how
+//many times every position came up, by hash
+func (b Board) gameResult(seen map[uint64]int) (string, bool) {
+	if len(b.moves(b.turn)) == 0 {
+		if !b.isKingInCheck(b.turn) {
+			return "1/2-1/2", true
+		}
+		if b.turn == white {
+			return "0-1", true
+		}
+		return "1-0", true
+	}
+
+	if b.halfMoves >= 100 || seen[b.Hash()] >= 3 || b.insufficientMaterial() {
+		return "1/2-1/2", true
+	}
+
+	return "", false
+}
+
+//nobody can mate with a lone minor piece
+func (b Board) insufficientMaterial() bool {
+	switch b.materialSide(white) + "v" + b.materialSide(black) {
+	case "KvK", "KNvK", "KvKN", "KBvK", "KvKB":
+		return true
+	}
+	return false
+}
+
+func resultValue(result string) float64 {
+	switch result {
+	case "1-0":
+		return 1
+	case "0-1":
+		return 0
+	}
+	return 0.5
+}
+
+//String writes the position as a line of plain text: the fen, the score
+//in centipawns and the result, like
+//
+//	rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1 | 35 | 0.5
+func (p TrainingPosition) String() string {
+	return fmt.Sprintf("%s | %d | %.1f", p.Board.FEN(), int(p.Score), p.Result)
+}
+
+//TrainingPositionSize is how many bytes a position takes in the binary format
+const TrainingPositionSize = 32
+
+//MarshalBinary packs the position in TrainingPositionSize bytes, little endian:
+//
+//	uint64 occupancy, bit 0 is a1 and bit 63 is h8
+//	4 bits for every piece in the same order, the low half of a byte first:
+//	the type from pawn (0) to king (5) plus 8 for black. a rook that can
+//	still castle is a 6
+//	uint8 side to move (128 for black) plus the en passant square (64 if none)
+//	uint8 halfmove clock, uint16 move number
+//	int16 score
+//	uint8 result, 0 if black won, 1 for a draw and 2 if white won
+//	uint8 unused
+func (p TrainingPosition) MarshalBinary() ([]byte, error) {
+	b := p.Board
+	if len(b.pieces) > 32 {
+		return nil, errors.New("too many pieces to pack")
+	}
+
+	castlingRooks := map[xy]bool{
+		{8, 1}: b.whiteCanCastle[0],
+		{1, 1}: b.whiteCanCastle[1],
+		{8, 8}: b.blackCanCastle[0],
+		{1, 8}: b.blackCanCastle[1],
+	}
+
+	var squares [64]byte
+	var occupancy uint64
+	for _, piece := range b.pieces {
+		code := byte(piece.pieceType)
+		if piece.pieceType == rook && castlingRooks[piece.position] {
+			code = 6
+		}
+		if piece.color == black {
+			code |= 8
+		}
+
+		index := piece.position.index()
+		squares[index] = code
+		occupancy |= 1 << uint(index)
+	}
+
+	data := make([]byte, TrainingPositionSize)
+	binary.LittleEndian.PutUint64(data[0:8], occupancy)
+	n := 0
+	for index := 0; index < 64; index++ {
+		if occupancy&(1<<uint(index)) == 0 {
+			continue
+		}
+		data[8+n/2] |= squares[index] << uint(4*(n%2))
+		n++
+	}
+
+	data[24] = 64
+	if (b.enPassant != xy{}) {
+		data[24] = byte(b.enPassant.index())
+	}
+	if b.turn == black {
+		data[24] |= 128
+	}
+
+	data[25] = byte(minInt(b.halfMoves, 255))
+	binary.LittleEndian.PutUint16(data[26:28], uint16(minInt(b.moveNumber, 65535)))
+	binary.LittleEndian.PutUint16(data[28:30], uint16(int16(maxScore(minScore(p.Score, 32767), -32767))))
+	data[30] = byte(p.Result * 2)
+
+	return data, nil
+}
+
+//UnmarshalBinary reads a position packed by MarshalBinary
+func (p *TrainingPosition) UnmarshalBinary(data []byte) error {
+	if len(data) != TrainingPositionSize {
+		return fmt.Errorf("a training position is %d bytes, not %d", TrainingPositionSize, len(data))
+	}
+
+	b := Board{pieces: make([]piece, 0, 32)}
+	occupancy := binary.LittleEndian.Uint64(data[0:8])
+	n := 0
+	for index := 0; index < 64; index++ {
+		if occupancy&(1<<uint(index)) == 0 {
+			continue
+		}
+		if n == 32 {
+			return errors.New("too many pieces in the occupancy")
+		}
+
+		code := data[8+n/2] >> uint(4*(n%2)) & 15
+		n++
+
+		position := xy{index%8 + 1, index/8 + 1}
+		col := color(code&8 == 0)
+		pt := pieceType(code & 7)
+		if pt == 6 {
+			pt = rook
+			switch position {
+			case xy{8, 1}:
+				b.whiteCanCastle[0] = true
+			case xy{1, 1}:
+				b.whiteCanCastle[1] = true
+			case xy{8, 8}:
+				b.blackCanCastle[0] = true
+			case xy{1, 8}:
+				b.blackCanCastle[1] = true
+			}
+		} else if pt > king {
+			return fmt.Errorf("%d isn't a piece", code)
+		}
+
+		b.pieces = append(b.pieces, piece{position, col, pt})
+	}
+
+	b.turn = data[24]&128 == 0
+	if square := int(data[24] & 127); square < 64 {
+		b.enPassant = xy{square%8 + 1, square/8 + 1}
+	}
+	b.halfMoves = int(data[25])
+	b.moveNumber = int(binary.LittleEndian.Uint16(data[26:28]))
+	b.initPSQ()
+
+	if data[30] > 2 {
+		return fmt.Errorf("%d isn't a result", data[30])
+	}
+
+	*p = TrainingPosition{
+		Board:  b,
+		Score:  Score(int16(binary.LittleEndian.Uint16(data[28:30]))),
+		Result: float64(data[30]) / 2,
+	}
+	return nil
+}
+
+//ParseTrainingPosition reads a line written by TrainingPosition.String
+func ParseTrainingPosition(line string) (TrainingPosition, error) {
+	fields := strings.Split(line, "|")
+	if len(fields) != 3 {
+		return TrainingPosition{}, fmt.Errorf("expected a fen, a score and a result in %q", line)
+	}
+
+	board, err := BoardFromFEN(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return TrainingPosition{}, err
+	}
+	score, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return TrainingPosition{}, err
+	}
+	result, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+	if err != nil || result < 0 || result > 1 {
+		return TrainingPosition{}, fmt.Errorf("%q isn't a result", fields[2])
+	}
+
+	return TrainingPosition{board, Score(score), result}, nil
+}
@@ -0,0 +1,98 @@
+package amatriciana
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTrainingPositionBinary(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/pp3ppp/8/3pP3/8/8/PPP2PPP/R3K2R w Qk d6 0 12",
+		"4k3/8/8/8/8/8/4P3/4K3 b - - 37 80",
+	}
+
+	for i, fen := range fens {
+		board, err := BoardFromFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		position := TrainingPosition{board, Score(-250 + 100*i), float64(i) / 2}
+		data, err := position.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != TrainingPositionSize {
+			t.Fatalf("expected %d bytes, got %d", TrainingPositionSize, len(data))
+		}
+
+		var read TrainingPosition
+		if err := read.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if read.String() != position.String() {
+			t.Errorf("expected %s, got %s", position, read)
+		}
+		if read.Board.Evaluate() != board.Evaluate() {
+			t.Errorf("%s: the read board evaluates to %v instead of %v", fen, read.Board.Evaluate(), board.Evaluate())
+		}
+
+		parsed, err := ParseTrainingPosition(position.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.String() != position.String() {
+			t.Errorf("expected %s, got %s", position, parsed)
+		}
+	}
+}
+
+func TestPlayTrainingGame(t *testing.T) {
+	positions, result := PlayTrainingGame(DatagenOptions{
+		Nodes:    300,
+		MaxPlies: 30,
+		Table:    NewTranspositionTable(1),
+		Random:   rand.New(rand.NewSource(7)),
+	})
+
+	if result != "1-0" && result != "0-1" && result != "1/2-1/2" {
+		t.Fatalf("%q isn't a result", result)
+	}
+	if len(positions) == 0 {
+		t.Fatal("no positions were kept")
+	}
+
+	for _, p := range positions {
+		if p.Result != resultValue(result) {
+			t.Errorf("%s: expected the result of the game %s", p, result)
+		}
+		if p.Board.isKingInCheck(p.Board.turn) || p.Score.IsMate() {
+			t.Errorf("%s shouldn't have been kept", p)
+		}
+	}
+}
+
+func TestGameResult(t *testing.T) {
+	tests := []struct {
+		fen    string
+		result string
+	}{
+		{"7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", "1-0"},
+		{"7k/8/6QK/8/8/8/8/8 b - - 0 1", "1/2-1/2"},
+		{"4k3/8/8/8/8/8/8/2B1K3 w - - 0 1", "1/2-1/2"},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 100 80", "1/2-1/2"},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 3 80", ""},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, _ := board.gameResult(map[uint64]int{})
+		if result != test.result {
+			t.Errorf("%s: expected %q, got %q", test.fen, test.result, result)
+		}
+	}
+}
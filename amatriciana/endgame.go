@@ -0,0 +1,268 @@
+package amatriciana
+
+import "strings"
+
+//some endings the normal evaluation gets wrong no matter how deep the search
+//goes: it doesn't know how to mate with bishop and knight, or that a pawn
+//can't be stopped. these know better, and get picked by the material on the board
+
+//what's known to be won is worth this much more than the material
+const knownWin Score = 1000
+
+//an endgame gives the score from the point of view of the strong side.
+//if ok is false it doesn't know anything special about the position
+type endgame func(b Board, strong color) (score Score, ok bool)
+
+//the endgames by material key, like KBNvK. they're all in there both ways around
+var endgames = map[string]endgame{}
+
+//endgames that only care about the pieces, whatever pawns are left
+var pawnlessEndgames = map[string]endgame{}
+
+func init() {
+	addEndgame(endgames, "KvK", drawnEndgame)
+	addEndgame(endgames, "KNvK", drawnEndgame)
+	addEndgame(endgames, "KBvK", drawnEndgame)
+	addEndgame(endgames, "KNNvK", drawnEndgame)
+	addEndgame(endgames, "KPvK", kpkEndgame)
+	addEndgame(endgames, "KBNvK", kbnkEndgame)
+	addEndgame(endgames, "KRvK", kxkEndgame)
+	addEndgame(endgames, "KQvK", kxkEndgame)
+
+	addEndgame(pawnlessEndgames, "KBvK", wrongRookPawnEndgame)
+	addEndgame(pawnlessEndgames, "KBvKB", oppositeBishopsEndgame)
+}
+
+//adds the endgame with the strong side as white and as black
+func addEndgame(table map[string]endgame, key string, eval endgame) {
+	sides := strings.Split(key, "v")
+	table[key] = eval
+	table[sides[1]+"v"+sides[0]] = eval
+}
+
+//the pieces of one side, like KRP. they go from the strongest down
+func (b Board) materialSide(col color) string {
+	var counts [6]int
+	for _, p := range b.pieces {
+		if p.color == col {
+			counts[p.pieceType]++
+		}
+	}
+
+	var side strings.Builder
+	for pt := king; pt >= pawn; pt-- {
+		side.WriteString(strings.Repeat(strings.ToUpper(string(pt.letter())), counts[pt]))
+	}
+	return side.String()
+}
+
+//evaluateEndgame looks for an endgame that knows the position and says which
+//one it used. the score is from white's point of view like Evaluate
+func (b Board) evaluateEndgame() (Score, string, bool) {
+	whiteSide, blackSide := b.materialSide(white), b.materialSide(black)
+	key := whiteSide + "v" + blackSide
+
+	eval, found := endgames[key]
+	strong := color(len(whiteSide) >= len(blackSide))
+
+	if !found && (whiteSide == "K" || blackSide == "K") && b.hasMatingMaterial(strong) {
+		eval, found = kxkEndgame, true
+	}
+
+	if !found {
+		whiteSide, blackSide = strings.TrimRight(whiteSide, "P"), strings.TrimRight(blackSide, "P")
+		key = whiteSide + "v" + blackSide
+		eval, found = pawnlessEndgames[key]
+		strong = color(b.countPieces(pawn, white) >= b.countPieces(pawn, black))
+	}
+
+	if !found {
+		return 0, "", false
+	}
+
+	score, ok := eval(b, strong)
+	if strong == black {
+		score = -score
+	}
+	return score, key, ok
+}
+
+func (b Board) countPieces(pt pieceType, col color) int {
+	count := 0
+	for _, p := range b.pieces {
+		if p.pieceType == pt && p.color == col {
+			count++
+		}
+	}
+	return count
+}
+
+//a queen or a rook does it, and so do two bishops that aren't on the same color
+func (b Board) hasMatingMaterial(col color) bool {
+	if b.countPieces(queen, col) > 0 || b.countPieces(rook, col) > 0 {
+		return true
+	}
+
+	var bishopColors [2]bool
+	for _, p := range b.pieces {
+		if p.pieceType == bishop && p.color == col {
+			bishopColors[p.position.squareColor()] = true
+		}
+	}
+	return bishopColors[0] && bishopColors[1]
+}
+
+//0 for dark squares, 1 for light ones
+func (a xy) squareColor() int {
+	return (a.x + a.y) % 2
+}
+
+func drawnEndgame(b Board, strong color) (Score, bool) {
+	return 0, true
+}
+
+//how far from the middle of the board a square is, 0 to 6
+func edgeDistance(a xy) int {
+	dx, dy := 2*a.x-9, 2*a.y-9
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx/2 + dy/2
+}
+
+func (b Board) kings(strong color) (xy, xy) {
+	strongKing, _ := b.kingPosition(strong)
+	weakKing, _ := b.kingPosition(!strong)
+	return strongKing, weakKing
+}
+
+//mating a lone king: push it to the edge and bring our own king over
+func kxkEndgame(b Board, strong color) (Score, bool) {
+	strongKing, weakKing := b.kings(strong)
+
+	score := b.Material(strong) + knownWin
+	score += 20 * Score(edgeDistance(weakKing))
+	score += 10 * Score(8-squareDistance(strongKing.index(), weakKing.index()))
+	return score, true
+}
+
+//bishop and knight only mate in the corners the bishop can reach
+func kbnkEndgame(b Board, strong color) (Score, bool) {
+	strongKing, weakKing := b.kings(strong)
+
+	corners := []xy{{1, 1}, {8, 8}}
+	for _, p := range b.pieces {
+		if p.pieceType == bishop && p.position.squareColor() == 1 {
+			corners = []xy{{1, 8}, {8, 1}}
+		}
+	}
+
+	cornerDistance := 14
+	for _, corner := range corners {
+		distance := abs(corner.x-weakKing.x) + abs(corner.y-weakKing.y)
+		if distance < cornerDistance {
+			cornerDistance = distance
+		}
+	}
+
+	score := b.Material(strong) + knownWin
+	score += 20 * Score(14-cornerDistance)
+	score += 10 * Score(8-squareDistance(strongKing.index(), weakKing.index()))
+	return score, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+//the bitbase knows if it's a win, then it's all about pushing the pawn
+func kpkEndgame(b Board, strong color) (Score, bool) {
+	strongKing, weakKing := b.kings(strong)
+
+	var pawnSquare xy
+	for _, p := range b.pieces {
+		if p.pieceType == pawn {
+			pawnSquare = p.position
+		}
+	}
+
+	//the bitbase has the pawn going up the board
+	flip := func(square int) int { return square }
+	if strong == black {
+		flip = func(square int) int { return square ^ 56 }
+	}
+
+	if !kpkProbe(b.turn == strong, flip(strongKing.index()), flip(weakKing.index()), flip(pawnSquare.index())) {
+		return 0, true
+	}
+
+	rank := flip(pawnSquare.index()) / 8
+	return 100 + knownWin + 10*Score(rank), true
+}
+
+//a bishop that can't cover the corner a rook pawn promotes on can't win
+//if the other king gets there first
+func wrongRookPawnEndgame(b Board, strong color) (Score, bool) {
+	if b.countPieces(pawn, !strong) > 0 {
+		return 0, false
+	}
+
+	file := 0
+	var bishopSquare xy
+	for _, p := range b.pieces {
+		if p.color != strong {
+			continue
+		}
+
+		switch p.pieceType {
+		case pawn:
+			if p.position.x != 1 && p.position.x != 8 || (file != 0 && file != p.position.x) {
+				return 0, false
+			}
+			file = p.position.x
+		case bishop:
+			bishopSquare = p.position
+		}
+	}
+
+	if file == 0 {
+		return 0, false
+	}
+
+	promotion := xy{file, 8}
+	if strong == black {
+		promotion = xy{file, 1}
+	}
+
+	_, weakKing := b.kings(strong)
+	if promotion.squareColor() != bishopSquare.squareColor() &&
+		squareDistance(promotion.index(), weakKing.index()) <= 1 {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+//with bishops on different colors and nothing else, even a pawn or two
+//up is usually a draw
+func oppositeBishopsEndgame(b Board, strong color) (Score, bool) {
+	var bishopColors [2]int
+	for _, p := range b.pieces {
+		if p.pieceType == bishop {
+			bishopColors[p.position.squareColor()]++
+		}
+	}
+
+	if bishopColors[0] != 1 {
+		return 0, false
+	}
+
+	score := b.Material(strong) - b.Material(!strong)
+	return score / 2, true
+}
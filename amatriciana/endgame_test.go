@@ -0,0 +1,92 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestKPKBitbase(t *testing.T) {
+	tests := []struct {
+		fen string
+		win bool
+	}{
+		//the king in front of the pawn on the sixth rank always wins
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", true},
+		//the same thing for black
+		{"8/8/8/8/4p3/4k3/8/4K3 b - - 0 1", true},
+		//the king gets to the corner in front of the rook pawn
+		{"k7/8/8/1K6/P7/8/8/8 w - - 0 1", false},
+		//the king is outside the square of the pawn
+		{"8/8/7k/P7/8/8/8/7K w - - 0 1", true},
+		//and here it's inside
+		{"8/8/3k4/8/8/8/P7/7K w - - 0 1", false},
+		//black takes the pawn
+		{"8/8/8/8/3kP3/8/8/7K b - - 0 1", false},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		eval := board.Evaluate()
+		if eval < 0 {
+			eval = -eval
+		}
+		if (eval > knownWin) != test.win {
+			t.Errorf("%s: expected a win to be %v, the evaluation is %v", test.fen, test.win, board.Evaluate())
+		}
+	}
+}
+
+func TestLoneKingGoesToTheEdge(t *testing.T) {
+	center, _ := BoardFromFEN("8/8/8/4k3/8/8/8/R3K3 w - - 0 1")
+	edge, _ := BoardFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+
+	if center.Evaluate() <= knownWin {
+		t.Error("KRK should be a known win, got", center.Evaluate())
+	}
+	if edge.Evaluate() <= center.Evaluate() {
+		t.Error("the king on the edge should be worse for black")
+	}
+}
+
+func TestKBNKGoesToTheRightCorner(t *testing.T) {
+	//the bishop is on a light square, so it mates on a8 or h1
+	right, _ := BoardFromFEN("k7/8/1K6/8/8/8/8/3BN3 w - - 0 1")
+	wrong, _ := BoardFromFEN("7k/8/6K1/8/8/8/8/3BN3 w - - 0 1")
+
+	if right.Evaluate() <= wrong.Evaluate() {
+		t.Errorf("a8 should be better than h8 for white: %v, %v", right.Evaluate(), wrong.Evaluate())
+	}
+}
+
+func TestDrawnEndgames(t *testing.T) {
+	fens := []string{
+		"8/8/4k3/8/8/3K4/8/5N2 w - - 0 1",
+		"8/8/4k3/8/8/3K4/8/3N1N2 w - - 0 1",
+		//the bishop doesn't cover a8 and the king is already there
+		"k7/8/8/8/8/P7/8/K1B5 w - - 0 1",
+	}
+
+	for _, fen := range fens {
+		board, err := BoardFromFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if board.Evaluate() != 0 {
+			t.Errorf("%s should be a draw, got %v", fen, board.Evaluate())
+		}
+	}
+}
+
+func TestOppositeBishops(t *testing.T) {
+	opposite, _ := BoardFromFEN("4k3/4b3/8/8/8/2PP4/8/3BK3 w - - 0 1")
+	same, _ := BoardFromFEN("4k3/5b2/8/8/8/2PP4/8/3BK3 w - - 0 1")
+
+	if opposite.Evaluate() >= same.Evaluate() {
+		t.Errorf("opposite bishops should be more drawish: %v, %v", opposite.Evaluate(), same.Evaluate())
+	}
+}
@@ -0,0 +1,295 @@
+package amatriciana
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//EPD is a position with operations about it, a line of a test suite like
+//
+//	r1b1kb1r/3q1ppp/pBp1pn2/8/Np3P2/5B2/PPP3PP/R2Q1RK1 w kq - bm Bxc6; id "WAC.005";
+type EPD struct {
+	Board      Board
+	Operations []EPDOperation
+}
+
+//EPDOperation is an opcode with its operands. the quotes around a string
+//operand aren't part of it
+type EPDOperation struct {
+	Opcode   string
+	Operands []string
+}
+
+//ParseEPD reads an epd line. the board only has the first four fields of a fen
+//there, the clocks come from the hmvc and fmvn operations if there are any.
+//the clocks of a full fen are fine too
+func ParseEPD(line string) (EPD, error) {
+	tokens, err := epdTokens(line)
+	if err != nil {
+		return EPD{}, err
+	}
+	if len(tokens) < 4 {
+		return EPD{}, fmt.Errorf("not enough fields in %q", line)
+	}
+
+	fields := tokens[:4]
+	clocks := []string{"0", "1"}
+	tokens = tokens[4:]
+	if len(tokens) >= 2 && isNumber(tokens[0]) && isNumber(tokens[1]) {
+		clocks = []string{tokens[0], tokens[1]}
+		tokens = tokens[2:]
+	}
+
+	var e EPD
+	var operation *EPDOperation
+	for _, token := range tokens {
+		switch {
+		case token == ";":
+			operation = nil
+		case operation == nil:
+			e.Operations = append(e.Operations, EPDOperation{Opcode: token})
+			operation = &e.Operations[len(e.Operations)-1]
+		default:
+			operation.Operands = append(operation.Operands, strings.Trim(token, `"`))
+		}
+	}
+
+	if hmvc, found := e.Operation("hmvc"); found && len(hmvc) == 1 && isNumber(hmvc[0]) {
+		clocks[0] = hmvc[0]
+	}
+	if fmvn, found := e.Operation("fmvn"); found && len(fmvn) == 1 && isNumber(fmvn[0]) {
+		clocks[1] = fmvn[0]
+	}
+
+	e.Board, err = BoardFromFEN(strings.Join(append(fields[:4:4], clocks...), " "))
+	if err != nil {
+		return EPD{}, err
+	}
+	return e, nil
+}
+
+//splits a line in words, quoted strings and semicolons. a quoted string
+//keeps its quotes so that it doesn't look like a semicolon or an opcode
+func epdTokens(line string) ([]string, error) {
+	var tokens []string
+	var word strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, char := range line {
+		switch {
+		case char == '"':
+			word.WriteRune(char)
+			if inQuotes {
+				flush()
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			word.WriteRune(char)
+		case char == ';':
+			flush()
+			tokens = append(tokens, ";")
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			flush()
+		default:
+			word.WriteRune(char)
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("a string isn't closed")
+	}
+	flush()
+
+	return tokens, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+//Operation gives back the operands of the first operation with opcode
+func (e EPD) Operation(opcode string) ([]string, bool) {
+	for _, operation := range e.Operations {
+		if operation.Opcode == opcode {
+			return operation.Operands, true
+		}
+	}
+	return nil, false
+}
+
+//ID is the id operation, empty if there isn't one
+func (e EPD) ID() string {
+	id, _ := e.Operation("id")
+	return strings.Join(id, " ")
+}
+
+//String writes the epd back, the clocks become operations if they aren't the usual ones
+func (e EPD) String() string {
+	fields := strings.Fields(e.Board.FEN())
+	line := strings.Join(fields[:4], " ")
+
+	hasOperation := func(opcode string) bool {
+		_, found := e.Operation(opcode)
+		return found
+	}
+	operations := e.Operations
+	if fields[4] != "0" && !hasOperation("hmvc") {
+		operations = append(operations, EPDOperation{"hmvc", []string{fields[4]}})
+	}
+	if fields[5] != "1" && !hasOperation("fmvn") {
+		operations = append(operations, EPDOperation{"fmvn", []string{fields[5]}})
+	}
+
+	for _, operation := range operations {
+		line += " " + operation.Opcode
+		for _, operand := range operation.Operands {
+			if operand == "" || strings.ContainsAny(operand, " ;\"") || isStringOpcode(operation.Opcode) {
+				operand = `"` + operand + `"`
+			}
+			line += " " + operand
+		}
+		line += ";"
+	}
+
+	return line
+}
+
+//ids, comments and the like always get quotes
+func isStringOpcode(opcode string) bool {
+	switch opcode {
+	case "id", "c0", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9", "eco", "nic":
+		return true
+	}
+	return false
+}
+
+//the moves in san of an operation like bm or am
+func (e EPD) moves(opcode string) ([]move, error) {
+	operands, _ := e.Operation(opcode)
+	moves := make([]move, 0, len(operands))
+	for _, san := range operands {
+		m, err := e.Board.parseSAN(san)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", opcode, san, err)
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+//points are the moves in a c0 like "f5=10, Be5+=2, Bf2=3", how sts gives
+//points for moves that aren't the best but aren't bad either
+func (e EPD) points() map[move]int {
+	c0, _ := e.Operation("c0")
+	points := make(map[move]int)
+	for _, item := range strings.Split(strings.Join(c0, " "), ",") {
+		equals := strings.LastIndexByte(item, '=')
+		if equals < 0 {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(item[equals+1:]))
+		if err != nil {
+			continue
+		}
+		m, err := e.Board.parseSAN(strings.TrimSpace(item[:equals]))
+		if err != nil {
+			continue
+		}
+		points[m] = value
+	}
+	return points
+}
+
+//EPDResult is how the search did on a position
+type EPDResult struct {
+	//Move is the move the search picked, in san
+	Move  string
+	Score Score
+	Depth int
+	Nodes int
+	//Solved is true if the move is one of the best moves (bm) and none of
+	//the moves to avoid (am)
+	Solved bool
+	//Points and MaxPoints are the points from the c0 operation like in sts,
+	//both 0 if there isn't one
+	Points    int
+	MaxPoints int
+}
+
+//Solve searches the position with opts and checks the move against the
+//operations. the position needs a bm or an am operation, or points in a c0
+func (e EPD) Solve(opts SearchOptions) (EPDResult, error) {
+	best, err := e.moves("bm")
+	if err != nil {
+		return EPDResult{}, err
+	}
+	avoid, err := e.moves("am")
+	if err != nil {
+		return EPDResult{}, err
+	}
+	points := e.points()
+	if len(best) == 0 && len(avoid) == 0 && len(points) == 0 {
+		return EPDResult{}, errors.New("there's no bm, am or c0 with points")
+	}
+
+	var depth, nodes int
+	info := opts.Info
+	opts.Info = func(i SearchInfo) {
+		depth, nodes = i.Depth, i.Nodes
+		if info != nil {
+			info(i)
+		}
+	}
+
+	lines := e.Board.Search(opts)
+	if len(lines) == 0 {
+		return EPDResult{}, errors.New("there are no legal moves")
+	}
+
+	played := lines[0].Move
+	result := EPDResult{
+		Move:   e.Board.san(played),
+		Score:  lines[0].Score,
+		Depth:  depth,
+		Nodes:  nodes,
+		Solved: len(best) > 0 || len(avoid) > 0,
+		Points: points[played],
+	}
+	if len(best) > 0 {
+		result.Solved = containsMove(best, played)
+	}
+	if containsMove(avoid, played) {
+		result.Solved = false
+	}
+	for _, p := range points {
+		result.MaxPoints = maxInt(result.MaxPoints, p)
+	}
+
+	return result, nil
+}
+
+func containsMove(moves []move, m move) bool {
+	for _, other := range moves {
+		if other == m {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
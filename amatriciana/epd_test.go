@@ -0,0 +1,99 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestParseEPD(t *testing.T) {
+	e, err := ParseEPD(`r1b1kb1r/3q1ppp/pBp1pn2/8/Np3P2/5B2/PPP3PP/R2Q1RK1 w kq - bm Bxc6; id "WAC.005"; c0 "a comment; with a semicolon"; acd 12; ce +135;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Board.FEN() != "r1b1kb1r/3q1ppp/pBp1pn2/8/Np3P2/5B2/PPP3PP/R2Q1RK1 w kq - 0 1" {
+		t.Errorf("unexpected board %s", e.Board.FEN())
+	}
+	if e.ID() != "WAC.005" {
+		t.Errorf("expected the id WAC.005, got %q", e.ID())
+	}
+	if c0, _ := e.Operation("c0"); len(c0) != 1 || c0[0] != "a comment; with a semicolon" {
+		t.Errorf("unexpected c0 %q", c0)
+	}
+	if ce, _ := e.Operation("ce"); len(ce) != 1 || ce[0] != "+135" {
+		t.Errorf("unexpected ce %q", ce)
+	}
+	if len(e.Operations) != 5 {
+		t.Errorf("expected 5 operations, got %d", len(e.Operations))
+	}
+
+	e, err = ParseEPD("4k3/8/8/8/8/8/4P3/4K3 w - - bm e4 e3; hmvc 7; fmvn 40;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Board.FEN() != "4k3/8/8/8/8/8/4P3/4K3 w - - 7 40" {
+		t.Errorf("the clocks should come from hmvc and fmvn, got %s", e.Board.FEN())
+	}
+	if bm, _ := e.Operation("bm"); len(bm) != 2 {
+		t.Errorf("expected two best moves, got %q", bm)
+	}
+
+	e, err = ParseEPD("4k3/8/8/8/8/8/4P3/4K3 b - - 12 50 id \"clocks\";")
+	if err != nil || e.Board.FEN() != "4k3/8/8/8/8/8/4P3/4K3 b - - 12 50" || e.ID() != "clocks" {
+		t.Errorf("a full fen should work too, got %s, %q and %v", e.Board.FEN(), e.ID(), err)
+	}
+
+	for _, line := range []string{"4k3/8/8/8/8/8/4P3/4K3 w -", `4k3/8/8/8/8/8/4P3/4K3 w - - id "open;`} {
+		if _, err := ParseEPD(line); err == nil {
+			t.Errorf("expected an error for %s", line)
+		}
+	}
+}
+
+func TestEPDString(t *testing.T) {
+	line := `4k3/8/8/8/8/8/4P3/4K3 w - - bm e4; id "test 1"; hmvc 7;`
+	e, err := ParseEPD(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != line {
+		t.Errorf("expected %s, got %s", line, e.String())
+	}
+
+	again, err := ParseEPD(e.String())
+	if err != nil || again.Board.FEN() != e.Board.FEN() {
+		t.Errorf("expected %s, got %s and %v", e.Board.FEN(), again.Board.FEN(), err)
+	}
+}
+
+func TestSolveEPD(t *testing.T) {
+	tests := []struct {
+		line      string
+		solved    bool
+		points    int
+		maxPoints int
+	}{
+		{`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - bm Ra8#; id "mate";`, true, 0, 0},
+		{`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - am Ra8#; id "avoid";`, false, 0, 0},
+		{`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - bm Ra8#; c0 "Ra8#=10, Kf1=3";`, true, 10, 10},
+		{`6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - c0 "Kf1=3, h3=1";`, false, 0, 3},
+	}
+
+	for _, test := range tests {
+		e, err := ParseEPD(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := e.Solve(SearchOptions{Depth: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Move != "Ra8#" || result.Solved != test.solved || result.Points != test.points || result.MaxPoints != test.maxPoints || result.Depth == 0 {
+			t.Errorf("%s: unexpected result %+v", test.line, result)
+		}
+	}
+
+	e, _ := ParseEPD("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - id \"nothing to check\";")
+	if _, err := e.Solve(SearchOptions{Depth: 1}); err == nil {
+		t.Error("expected an error without bm, am or c0")
+	}
+}
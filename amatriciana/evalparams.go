@@ -0,0 +1,198 @@
+package amatriciana
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//all the weights of the evaluation, so they can be changed without recompiling.
+//the piece-square tables aren't in here: the board keeps their sum up to date
+//while it moves, so they can't change from one evaluator to the other
+
+//Tapered is a weight in centipawns for the middlegame and for the endgame
+type Tapered struct {
+	Mg int `json:"mg"`
+	Eg int `json:"eg"`
+}
+
+func (t Tapered) score() taperedScore {
+	return taperedScore{t.Mg, t.Eg}
+}
+
+//EvalParams are the weights of the evaluation, in centipawns.
+//arrays by rank go from the first (the back rank) to the eighth
+//from the point of view of the side the piece belongs to
+type EvalParams struct {
+	PawnValue        int `json:"pawn_value"`
+	KnightValue      int `json:"knight_value"`
+	BishopValue      int `json:"bishop_value"`
+	RookValue        int `json:"rook_value"`
+	QueenValue       int `json:"queen_value"`
+	BishopPair       int `json:"bishop_pair"`
+	RookOpenFile     int `json:"rook_open_file"`
+	RookSemiOpenFile int `json:"rook_semi_open_file"`
+	//for every time a piece attacks one of the four central squares
+	CenterAttacker int `json:"center_attacker"`
+
+	DoubledPawn   Tapered    `json:"doubled_pawn"`
+	IsolatedPawn  Tapered    `json:"isolated_pawn"`
+	BackwardPawn  Tapered    `json:"backward_pawn"`
+	ConnectedPawn [8]int     `json:"connected_pawn"`
+	PassedPawn    [8]Tapered `json:"passed_pawn"`
+	CandidatePawn [8]Tapered `json:"candidate_pawn"`
+	//how much the kings' distance from a passed pawn counts in the endgame
+	PassedPawnKings [8]int `json:"passed_pawn_kings"`
+
+	//own pawns one and two ranks in front of the king, or none
+	PawnShield [3]int `json:"pawn_shield"`
+	//enemy pawns coming up the files next to the king, by how far they are
+	PawnStorm        [5]int `json:"pawn_storm"`
+	SemiOpenKingFile int    `json:"semi_open_king_file"`
+	OpenKingFile     int    `json:"open_king_file"`
+	//by piece type, for every square of the king zone a piece hits
+	KingAttackWeights [6]int `json:"king_attack_weights"`
+	//by piece type, for every safe square a piece can check from
+	SafeCheckWeights [6]int `json:"safe_check_weights"`
+	//the most king safety can take away
+	MaxKingDanger int `json:"max_king_danger"`
+
+	//by piece type, for every square over (or under) the usual number
+	Mobility [6]Tapered `json:"mobility"`
+	//how many squares a piece usually reaches, by piece type
+	UsualMobility [6]int  `json:"usual_mobility"`
+	KnightOutpost Tapered `json:"knight_outpost"`
+	BishopOutpost Tapered `json:"bishop_outpost"`
+	RookOnSeventh Tapered `json:"rook_on_seventh"`
+	TrappedRook   Tapered `json:"trapped_rook"`
+	TrappedBishop Tapered `json:"trapped_bishop"`
+}
+
+//DefaultEvalParams are the weights Evaluate uses
+func DefaultEvalParams() EvalParams {
+	return EvalParams{
+		PawnValue:        100,
+		KnightValue:      300,
+		BishopValue:      300,
+		RookValue:        500,
+		QueenValue:       900,
+		BishopPair:       100,
+		RookOpenFile:     50,
+		RookSemiOpenFile: 20,
+		CenterAttacker:   50,
+
+		DoubledPawn:     Tapered{-10, -25},
+		IsolatedPawn:    Tapered{-10, -15},
+		BackwardPawn:    Tapered{-8, -12},
+		ConnectedPawn:   [8]int{0, 0, 5, 8, 12, 25, 45, 0},
+		PassedPawn:      [8]Tapered{{0, 0}, {0, 0}, {5, 10}, {10, 20}, {20, 35}, {35, 60}, {60, 100}, {0, 0}},
+		CandidatePawn:   [8]Tapered{{0, 0}, {0, 0}, {3, 5}, {5, 10}, {10, 18}, {18, 30}, {0, 0}, {0, 0}},
+		PassedPawnKings: [8]int{0, 0, 0, 1, 2, 3, 4, 0},
+
+		PawnShield:        [3]int{-12, 12, 6},
+		PawnStorm:         [5]int{0, -5, -25, -15, -8},
+		SemiOpenKingFile:  -12,
+		OpenKingFile:      -10,
+		KingAttackWeights: [6]int{pawn: 0, knight: 2, bishop: 2, rook: 3, queen: 5, king: 0},
+		SafeCheckWeights:  [6]int{pawn: 0, knight: 3, bishop: 2, rook: 3, queen: 4, king: 0},
+		MaxKingDanger:     500,
+
+		Mobility:      [6]Tapered{knight: {4, 4}, bishop: {5, 5}, rook: {2, 4}, queen: {1, 2}},
+		UsualMobility: [6]int{knight: 4, bishop: 6, rook: 7, queen: 13},
+		KnightOutpost: Tapered{25, 15},
+		BishopOutpost: Tapered{15, 8},
+		RookOnSeventh: Tapered{20, 30},
+		TrappedRook:   Tapered{-40, -10},
+		TrappedBishop: Tapered{-100, -80},
+	}
+}
+
+//LoadEvalParams reads the weights from a json file, or a toml one if the name
+//ends in .toml. whatever the file leaves out keeps its default value
+func LoadEvalParams(path string) (EvalParams, error) {
+	params := DefaultEvalParams()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return params, err
+	}
+
+	if isTOML(path) {
+		data, err = tomlToJSON(data)
+		if err != nil {
+			return params, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&params); err != nil {
+		return DefaultEvalParams(), fmt.Errorf("%s: %v", path, err)
+	}
+
+	return params, nil
+}
+
+//Save writes the weights to a json file, or a toml one if the name ends in .toml
+func (p EvalParams) Save(path string) error {
+	if isTOML(path) {
+		return ioutil.WriteFile(path, encodeTOML(p), 0644)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func isTOML(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".toml"
+}
+
+//EvalParam is a single weight, with a name like PassedPawn6Eg
+type EvalParam struct {
+	Name  string
+	Value *int
+}
+
+//List gives every single weight in p, in the order they're declared.
+//arrays get the index after the name and tapered weights Mg or Eg at the end
+func (p *EvalParams) List() []EvalParam {
+	var list []EvalParam
+
+	var walk func(name string, v reflect.Value)
+	walk = func(name string, v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Int:
+			list = append(list, EvalParam{name, v.Addr().Interface().(*int)})
+		case reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(name+strconv.Itoa(i), v.Index(i))
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				walk(name+v.Type().Field(i).Name, v.Field(i))
+			}
+		}
+	}
+	walk("", reflect.ValueOf(p).Elem())
+
+	return list
+}
+
+//Set changes the weight called name, the same names as List without caring about case
+func (p *EvalParams) Set(name string, value int) error {
+	for _, param := range p.List() {
+		if strings.EqualFold(param.Name, name) {
+			*param.Value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("there's no evaluation weight called %s", name)
+}
@@ -0,0 +1,106 @@
+package amatriciana
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvalParamsFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evalparams")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	params := DefaultEvalParams()
+	params.KnightValue = 325
+	params.PassedPawn[5].Eg = 70
+	params.PawnStorm[2] = -30
+
+	for _, name := range []string{"params.json", "params.toml"} {
+		path := filepath.Join(dir, name)
+		if err := params.Save(path); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadEvalParams(path)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if loaded != params {
+			t.Errorf("%s: expected %+v, got %+v", name, params, loaded)
+		}
+	}
+}
+
+func TestEvalParamsPartialTOML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "evalparams")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "params.toml")
+	toml := `# only what changes
+queen_value = 950
+doubled_pawn = { mg = -15, eg = -30 }
+pawn_shield = [
+	-10, # no pawn
+	15,
+	5,
+]
+`
+	if err := ioutil.WriteFile(path, []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := DefaultEvalParams()
+	expected.QueenValue = 950
+	expected.DoubledPawn = Tapered{-15, -30}
+	expected.PawnShield = [3]int{-10, 15, 5}
+	if loaded != expected {
+		t.Errorf("expected %+v, got %+v", expected, loaded)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("queen_valu = 950\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEvalParams(path); err == nil {
+		t.Error("a misspelled weight should be an error")
+	}
+}
+
+func TestEvalParamsSet(t *testing.T) {
+	params := DefaultEvalParams()
+	if err := params.Set("passedpawn6eg", 120); err != nil {
+		t.Fatal(err)
+	}
+	if params.PassedPawn[6].Eg != 120 {
+		t.Error("expected the passed pawn on the seventh to change, got", params.PassedPawn[6])
+	}
+	if err := params.Set("NoSuchWeight", 1); err == nil {
+		t.Error("setting a weight that doesn't exist should be an error")
+	}
+}
+
+func TestEvaluatorWeights(t *testing.T) {
+	board, _ := BoardFromFEN("4k3/ppp5/8/8/8/8/PPPQQ3/4K3 w - - 0 1")
+
+	params := DefaultEvalParams()
+	params.QueenValue += 100
+	heavy := NewEvaluator(params)
+
+	if diff := heavy.Evaluate(board) - board.Evaluate(); diff != 200 {
+		t.Errorf("two queens worth a pawn more each should add two pawns, got %v", diff)
+	}
+	if NewEvaluator(DefaultEvalParams()).Evaluate(board) != board.Evaluate() {
+		t.Error("the default weights should give the same evaluation as Board.Evaluate")
+	}
+}
@@ -1,9 +1,7 @@
 package amatriciana
 
-import "fmt"
-
 type Evaluation struct {
-	Advantage float32
+	Advantage Score
 	MateIn    int
 }
 
@@ -15,7 +13,7 @@ func (b Board) bestMove() move {
 	}
 
 	bestMove := moves[0]
-	var biggestAdvantage float32 = -2000.0
+	biggestAdvantage := -mateScore - 1
 
 	for _, move := range moves {
 		newBoard := b.Clone()
@@ -44,119 +42,239 @@ func (b Board) BestMoveString() string {
 }
 
 //possible things to help evaluation:
-//protector overloading
-//doubled pawns
-//king exposed (only until middlegame)
-//piece mobility (unless protecting something)
-//pawn chains
-//passed pawns
-
-func (b Board) Evaluate() float32 {
+//protector overloading (tactics.go finds overloaded defenders, the evaluation doesn't use them yet)
+
+//the parts of the evaluation, every one of them is kept by color
+type evaluationTerm int
+
+const (
+	materialTerm evaluationTerm = iota
+	pieceSquaresTerm
+	pawnsTerm
+	kingSafetyTerm
+	mobilityTerm
+	centerControlTerm
+	termCount
+)
+
+var termNames = [termCount]string{
+	materialTerm:      "material",
+	pieceSquaresTerm:  "piece squares",
+	pawnsTerm:         "pawns",
+	kingSafetyTerm:    "king safety",
+	mobilityTerm:      "mobility",
+	centerControlTerm: "center control",
+}
+
+//Evaluator scores positions with its own weights. it can be used from more
+//than one goroutine at the same time
+type Evaluator struct {
+	params EvalParams
+	//by how many attack units there are on the king
+	kingDanger [100]int
+	pawnTable  []pawnSlot
+	//if there's a network it does the evaluating instead of the weights
+	network *Network
+}
+
+//NewEvaluator makes an evaluator with params as weights
+func NewEvaluator(params EvalParams) *Evaluator {
+	e := &Evaluator{
+		params:    params,
+		pawnTable: make([]pawnSlot, pawnTableSize),
+	}
+	e.initKingDanger()
+	return e
+}
+
+//NewNetworkEvaluator makes an evaluator that asks the network. the known
+//endgames and checkmates still get scored the classic way
+func NewNetworkEvaluator(network *Network) *Evaluator {
+	e := NewEvaluator(DefaultEvalParams())
+	e.network = network
+	return e
+}
+
+//what Board.Evaluate and the search use when nobody says otherwise
+var defaultEvaluator = NewEvaluator(DefaultEvalParams())
+
+//Params are the weights the evaluator uses
+func (e *Evaluator) Params() EvalParams {
+	return e.params
+}
+
+//everything the evaluation worked out about a position
+type evaluation struct {
+	terms     [termCount][2]taperedScore
+	checkmate bool
+	//the endgame that knew the position better, if any
+	endgame string
+	score   Score
+}
+
+//the one place the evaluation happens, Evaluate and EvaluateDetailed both go through here
+func (ev *Evaluator) evaluate(b Board) evaluation {
+	var e evaluation
+
 	if b.IsCheckmate() {
+		e.checkmate = true
 		if b.turn == white {
-			return -1000
+			e.score = -mateScore
 		} else {
-			return 1000
+			e.score = mateScore
 		}
+		return e
 	}
 
-	whiteMaterial := b.Material(white)
-	blackMaterial := b.Material(black)
+	if score, key, ok := b.evaluateEndgame(); ok {
+		e.endgame = key
+		e.score = score
+		return e
+	}
 
-	material := whiteMaterial - blackMaterial
+	if ev.network != nil {
+		e.score = ev.network.evaluate(b)
+		return e
+	}
 
-	centerControlWhite := float32(b.HowManyAttack(xy{4, 4}, white)+
-		b.HowManyAttack(xy{4, 5}, white)+
-		b.HowManyAttack(xy{5, 4}, white)+
-		b.HowManyAttack(xy{5, 5}, white)) / 4
+	//everything that needs to know what the pieces attack shares the same map
+	m := b.attackMap()
 
-	centerControlBlack := float32(b.HowManyAttack(xy{4, 4}, black)+
-		b.HowManyAttack(xy{4, 5}, black)+
-		b.HowManyAttack(xy{5, 4}, black)+
-		b.HowManyAttack(xy{5, 5}, black)) / 4
+	for _, col := range [...]color{white, black} {
+		material := ev.material(b, col)
+		center := b.centerAttackers(m, col) * ev.params.CenterAttacker
+		e.terms[materialTerm][col.index()] = taperedScore{material, material}
+		e.terms[centerControlTerm][col.index()] = taperedScore{center, center}
+	}
+	e.terms[pieceSquaresTerm] = b.psq
+	e.terms[pawnsTerm] = ev.pawnStructure(b)
+	e.terms[kingSafetyTerm] = ev.kingSafety(b, m)
+	e.terms[mobilityTerm] = ev.mobility(b, m)
+
+	var total taperedScore
+	for _, term := range e.terms {
+		total.add(whiteMinusBlack(term))
+	}
+	e.score = Score(total.taper(b.phase))
 
-	centerControl := centerControlWhite - centerControlBlack
+	return e
+}
 
-	doubledPawnsWhite := float32(b.FilesWithDoubledPawns(white)) * 0.3
-	doubledPawnsBlack := float32(b.FilesWithDoubledPawns(black)) * 0.3
+//Evaluate is the score of the position from white's point of view
+func (e *Evaluator) Evaluate(b Board) Score {
+	return e.evaluate(b).score
+}
 
-	doubledPawns := doubledPawnsWhite - doubledPawnsBlack
+//Evaluate scores the position with the default weights
+func (b Board) Evaluate() Score {
+	return defaultEvaluator.Evaluate(b)
+}
 
-	return material + float32(centerControl)*2 + float32(doubledPawns)*0.3
+//PhaseScore is a score for the middlegame and one for the endgame
+type PhaseScore struct {
+	Middlegame Score `json:"middlegame"`
+	Endgame    Score `json:"endgame"`
 }
 
-func (b Board) EvaluateVerbose() float32 {
-	if b.IsCheckmate() {
-		if b.turn == white {
-			return -1000
-		} else {
-			return 1000
-		}
-	}
+//EvaluationTerm is one part of the evaluation. the sides are each from their own
+//point of view, Total is white minus black blended by the phase
+type EvaluationTerm struct {
+	Name  string     `json:"name"`
+	White PhaseScore `json:"white"`
+	Black PhaseScore `json:"black"`
+	Total Score      `json:"total"`
+}
 
-	whiteMaterial := b.Material(white)
-	blackMaterial := b.Material(black)
+//EvaluationDetails is everything that went into Evaluate. the terms' totals
+//can be a centipawn off from Total because each one gets rounded on its own
+type EvaluationDetails struct {
+	Terms []EvaluationTerm `json:"terms,omitempty"`
+	//from 0 with only kings and pawns to 24 with everything on the board
+	Phase     int  `json:"phase"`
+	Checkmate bool `json:"checkmate"`
+	//set when an endgame like KPvK knew better than the terms, which are left out
+	Endgame string `json:"endgame,omitempty"`
+	Total   Score  `json:"total"`
+}
 
-	material := whiteMaterial - blackMaterial
-	fmt.Println("material:", material)
+func (s taperedScore) phaseScore() PhaseScore {
+	return PhaseScore{Score(s.mg), Score(s.eg)}
+}
 
-	centerControlWhite := (b.HowManyAttack(xy{4, 4}, white) +
-		b.HowManyAttack(xy{4, 5}, white) +
-		b.HowManyAttack(xy{5, 4}, white) +
-		b.HowManyAttack(xy{5, 5}, white)) / 4
+//EvaluateDetailed is Evaluate with the default weights, term by term
+func (b Board) EvaluateDetailed() EvaluationDetails {
+	return defaultEvaluator.EvaluateDetailed(b)
+}
 
-	centerControlBlack := (b.HowManyAttack(xy{4, 4}, black) +
-		b.HowManyAttack(xy{4, 5}, black) +
-		b.HowManyAttack(xy{5, 4}, black) +
-		b.HowManyAttack(xy{5, 5}, black)) / 4
+//EvaluateDetailed is Evaluate term by term. Total is always what Evaluate gives
+func (ev *Evaluator) EvaluateDetailed(b Board) EvaluationDetails {
+	e := ev.evaluate(b)
+	details := EvaluationDetails{
+		Phase:     minInt(b.phase, maxPhase),
+		Checkmate: e.checkmate,
+		Endgame:   e.endgame,
+		Total:     e.score,
+	}
+	if e.checkmate || e.endgame != "" {
+		return details
+	}
 
-	centerControl := centerControlWhite - centerControlBlack
-	fmt.Println("centerControl:", centerControl)
+	//there's no telling what the network thinks of every piece
+	if ev.network != nil {
+		details.Terms = []EvaluationTerm{{Name: "network", Total: e.score}}
+		return details
+	}
 
-	doubledPawnsWhite := float32(b.FilesWithDoubledPawns(white)) * 0.3
-	doubledPawnsBlack := float32(b.FilesWithDoubledPawns(black)) * 0.3
+	for term, scores := range e.terms {
+		details.Terms = append(details.Terms, EvaluationTerm{
+			Name:  termNames[term],
+			White: scores[0].phaseScore(),
+			Black: scores[1].phaseScore(),
+			Total: Score(whiteMinusBlack(scores).taper(b.phase)),
+		})
+	}
 
-	doubledPawns := doubledPawnsWhite - doubledPawnsBlack
-	fmt.Println("doubledPawns:", doubledPawns)
+	return details
+}
 
-	return material + float32(centerControl)*2 + float32(doubledPawns)*0.3
+//Material is what col's pieces are worth with the default weights
+func (b Board) Material(col color) Score {
+	return Score(defaultEvaluator.material(b, col))
 }
 
-func (b Board) Material(col color) float32 {
+//in centipawns
+func (e *Evaluator) material(b Board, col color) int {
+	params := &e.params
 	pieces := b.piecesOfColor(col)
-	var output float32 = 0.0
+	output := 0
 
 	howManyBishops := 0
 
 	for _, piece := range pieces {
 		switch piece.pieceType {
 		case pawn:
-			output++
-			chain := float32(b.PawnChain(piece.position, piece.color))
-			if chain > 1 {
-				output += chain * 0.2
-			}
+			output += params.PawnValue
 		case knight:
-			output += 3.0
+			output += params.KnightValue
 		case bishop:
-			output += 3.0
+			output += params.BishopValue
 			howManyBishops++
 		case rook:
-			output += 5.0
+			output += params.RookValue
 
 			//check if it's on an open or semiopen file
 			if b.IsFileOpen(piece.position.x) {
-				output += 0.5
+				output += params.RookOpenFile
 			} else if b.IsFileSemiOpen(piece.position.x, piece.color) {
-				output += 0.2
+				output += params.RookSemiOpenFile
 			}
 		case queen:
-			output += 9.0
+			output += params.QueenValue
 		}
 	}
 
-	if howManyBishops == 2.0 {
-		output += 1.0
+	if howManyBishops == 2 {
+		output += params.BishopPair
 	}
 
 	return output
@@ -301,41 +419,21 @@ func (b Board) PawnChain(pawnPos xy, col color) int {
 	}
 }
 
+//HowManyAttack tells you how many pieces of col attack square
 func (b Board) HowManyAttack(square xy, col color) int {
-	moves := b.moves(col)
-
-	attackingPieces := 0
-	for _, move := range moves {
-		if move.to == square {
-			attackingPieces++
-		}
-	}
-
-	for _, piece := range b.pieces {
-		if piece.pieceType == pawn {
-			captures := b.pawnCaptures(piece.position, col)
-			for _, capture := range captures {
-				if capture.to == square {
-					attackingPieces++
-				}
-			}
-		}
-	}
-
-	return attackingPieces
+	return b.attackersCount(b.attackMap(), square, col)
 }
 
-func (b Board) CenterControl(col color) float32 {
-	var output float32 = 0.0
-	centralSquares := [...]string{"e4", "e5", "d4", "d5"}
-
-	for _, squareCoord := range centralSquares {
-		square, _ := parsexy(squareCoord)
-		attackers := b.HowManyAttack(square, col)
-
-		output += float32(attackers)
+func (b Board) CenterControl(col color) int {
+	return b.centerAttackers(b.attackMap(), col)
+}
 
+//how many times col attacks the four central squares
+func (b Board) centerAttackers(m attackMap, col color) int {
+	attackers := 0
+	for _, square := range [...]xy{{4, 4}, {4, 5}, {5, 4}, {5, 5}} {
+		attackers += b.attackersCount(m, square, col)
 	}
 
-	return output
+	return attackers
 }
@@ -58,17 +58,90 @@ func TestCheckmateEvaluation(t *testing.T) {
 		t.Fail()
 	}
 
-	if board.Evaluate() != 1000 {
+	if board.Evaluate() != mateScore {
 		t.Fail()
 	}
 	fmt.Println("board evaluation:", board.Evaluate())
 }
 
+func TestScoreFormats(t *testing.T) {
+	tests := []struct {
+		score  Score
+		text   string
+		uci    string
+		mateIn int
+	}{
+		{125, "+1.25", "cp 125", 0},
+		{-40, "-0.40", "cp -40", 0},
+		{0, "+0.00", "cp 0", 0},
+		{mateScore - 1, "#1", "mate 1", 1},
+		{mateScore - 4, "#2", "mate 2", 2},
+		{-mateScore + 2, "#-1", "mate -1", -1},
+		{tbWinScore, "+318.72", "cp 31872", 0},
+	}
+
+	for _, test := range tests {
+		if test.score.String() != test.text || test.score.UCI() != test.uci || test.score.MateIn() != test.mateIn {
+			t.Errorf("%d: expected %s, %s and mate in %d, got %s, %s and %d", int(test.score),
+				test.text, test.uci, test.mateIn, test.score.String(), test.score.UCI(), test.score.MateIn())
+		}
+
+		parsed, err := ParseScore(test.uci)
+		if err != nil || parsed.UCI() != test.uci {
+			t.Errorf("%s: read back as %s, %v", test.uci, parsed.UCI(), err)
+		}
+	}
+
+	if _, err := ParseScore("depth 3"); err == nil {
+		t.Error("expected an error for depth 3")
+	}
+}
+
 func TestMaxMin(t *testing.T) {
-	if max(3.0, 5.0) != 5.0 {
+	if maxScore(3, 5) != 5 {
 		t.Fail()
 	}
-	if min(3.0, 5.0) != 3.0 {
+	if minScore(3, 5) != 3 {
 		t.Fail()
 	}
 }
+
+func TestEvaluateDetailed(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+		"4rkn1/p1Q2p1q/8/2pp4/5P2/1P4P1/PBbKB3/8 b - - 0 20",
+		"8/8/8/4k3/8/8/8/R3K3 w - - 0 1",
+		"R1k5/6R1/8/8/8/3K4/8/8 b - - 11 6",
+	}
+
+	for _, fen := range fens {
+		board, err := BoardFromFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		details := board.EvaluateDetailed()
+		if details.Total != board.Evaluate() {
+			t.Errorf("%s: the details say %v, Evaluate says %v", fen, details.Total, board.Evaluate())
+		}
+		if details.Checkmate || details.Endgame != "" {
+			continue
+		}
+
+		var sum Score
+		for _, term := range details.Terms {
+			sum += term.Total
+		}
+		if diff := int(sum - details.Total); abs(diff) > len(details.Terms) {
+			t.Errorf("%s: the terms add up to %v, the total is %v", fen, sum, details.Total)
+		}
+	}
+
+	start := NewBoard().EvaluateDetailed()
+	for _, term := range start.Terms {
+		if term.White != term.Black {
+			t.Errorf("%s should be the same for both sides at the start: %v, %v", term.Name, term.White, term.Black)
+		}
+	}
+}
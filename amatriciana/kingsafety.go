@@ -0,0 +1,112 @@
+package amatriciana
+
+import "math/bits"
+
+//king safety only matters while there's enough stuff on the board to mate,
+//so all of it is middlegame score and it goes away with the material
+
+//a couple of attackers are nothing to worry about, a lot of them are deadly
+func (e *Evaluator) initKingDanger() {
+	for units := range e.kingDanger {
+		e.kingDanger[units] = minInt(units*units/4, e.params.MaxKingDanger)
+	}
+}
+
+//KingSafety is the king safety part of the evaluation, from white's point of view
+func (b Board) KingSafety() Score {
+	return Score(whiteMinusBlack(defaultEvaluator.kingSafety(b, b.attackMap())).taper(b.phase))
+}
+
+//by color
+func (e *Evaluator) kingSafety(b Board, m attackMap) [2]taperedScore {
+	return [2]taperedScore{{e.kingSafetyOf(b, white, m), 0}, {e.kingSafetyOf(b, black, m), 0}}
+}
+
+func (e *Evaluator) kingSafetyOf(b Board, col color, m attackMap) int {
+	params := &e.params
+	us, them := col.index(), (!col).index()
+	kings := m.pieces[us][king]
+	if kings == 0 {
+		return 0
+	}
+	kingSquare := bits.TrailingZeros64(kings)
+	kingPos := squareXY(kingSquare)
+
+	forward := 1
+	if col == black {
+		forward = -1
+	}
+
+	score := 0
+	for file := kingPos.x - 1; file <= kingPos.x+1; file++ {
+		if file < 1 || file > 8 {
+			continue
+		}
+
+		ours, theirs := 0, 0
+		for distance := 1; distance <= 4; distance++ {
+			square := xy{file, kingPos.y + forward*distance}
+			if !isInBounds(square) {
+				break
+			}
+
+			if ours == 0 && m.pieces[us][pawn]&square.bit() != 0 {
+				ours = distance
+			}
+			if theirs == 0 && m.pieces[them][pawn]&square.bit() != 0 {
+				theirs = distance
+			}
+		}
+
+		if ours > 0 && ours <= 2 {
+			score += params.PawnShield[ours]
+		} else {
+			score += params.PawnShield[0]
+		}
+		score += params.PawnStorm[theirs]
+
+		fileMask := uint64(0x0101010101010101) << uint(file-1)
+		if m.pieces[us][pawn]&fileMask == 0 {
+			score += params.SemiOpenKingFile
+			if m.pieces[them][pawn]&fileMask == 0 {
+				score += params.OpenKingFile
+			}
+		}
+	}
+
+	//the pieces hitting the squares around the king
+	zone := kingAttacks[kingSquare] | 1<<uint(kingSquare)
+	attackers, units := 0, 0
+	for i, p := range b.pieces {
+		if p.color == col {
+			continue
+		}
+
+		hits := m.byPiece[i] & zone
+		if hits != 0 && params.KingAttackWeights[p.pieceType] > 0 {
+			attackers++
+			units += params.KingAttackWeights[p.pieceType] * bits.OnesCount64(hits)
+		}
+	}
+
+	//squares the enemy can check from without getting taken
+	safe := ^m.all[us] &^ m.byColor[them]
+	diagonals := slidingAttacks(kingSquare, bishopDirections, m.occupied)
+	lines := slidingAttacks(kingSquare, rookDirections, m.occupied)
+	checks := [...]uint64{
+		knight: knightAttacks[kingSquare],
+		bishop: diagonals,
+		rook:   lines,
+		queen:  diagonals | lines,
+	}
+	for pt, squares := range checks {
+		units += params.SafeCheckWeights[pt] * bits.OnesCount64(squares&m.byType[them][pt]&safe)
+	}
+
+	//a single attacker can't do much on its own
+	if attackers < 2 {
+		units /= 2
+	}
+
+	return score - e.kingDanger[minInt(units, len(e.kingDanger)-1)]
+}
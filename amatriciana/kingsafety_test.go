@@ -0,0 +1,54 @@
+package amatriciana
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestAttackMap(t *testing.T) {
+	board, _ := BoardFromFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	m := board.attackMap()
+
+	//the rook gets the a file and stops at the king on e1
+	rook := m.byType[0][rook]
+	if bits.OnesCount64(rook) != 11 {
+		t.Error("the rook should attack 11 squares, got", bits.OnesCount64(rook))
+	}
+	if rook&(xy{5, 1}).bit() == 0 || rook&(xy{6, 1}).bit() != 0 {
+		t.Error("the rook should stop on e1")
+	}
+
+	board, _ = BoardFromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	m = board.attackMap()
+	if m.all[0]&(uint64(0xff)<<16) != uint64(0xff)<<16 {
+		t.Error("white should attack all of the third rank")
+	}
+	if m.all[0]&(uint64(0xff)<<24) != 0 {
+		t.Error("white shouldn't attack anything on the fourth rank")
+	}
+}
+
+func TestKingSafety(t *testing.T) {
+	even, _ := BoardFromFEN("r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1")
+	if even.KingSafety() != 0 {
+		t.Error("the same castled kings should be even, got", even.KingSafety())
+	}
+
+	pushed, _ := BoardFromFEN("r1bq1rk1/pppp1ppp/2n2n2/2b1p3/2B1P1P1/2N2N2/PPPP1P1P/R1BQ1RK1 w - - 0 1")
+	if pushed.KingSafety() >= even.KingSafety() {
+		t.Error("pushing the pawns in front of the king should make it less safe")
+	}
+
+	//the queen and the knight are right next to the king
+	attacked, _ := BoardFromFEN("r1b2rk1/pppp1ppp/2n5/2b1p1NQ/2B1P3/2N5/PPPP1PPP/R1B2RK1 w - - 0 1")
+	quiet, _ := BoardFromFEN("r1b2rk1/pppp1ppp/2n5/2b1p3/2B1P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1")
+	if defaultEvaluator.kingSafetyOf(attacked, black, attacked.attackMap()) >= defaultEvaluator.kingSafetyOf(quiet, black, quiet.attackMap()) {
+		t.Error("the black king should be in more danger with the pieces around it")
+	}
+
+	//and it doesn't count in the endgame
+	endgame, _ := BoardFromFEN("6k1/8/8/8/8/8/8/6K1 w - - 0 1")
+	if endgame.KingSafety() != 0 {
+		t.Error("no king safety without pieces, got", endgame.KingSafety())
+	}
+}
@@ -0,0 +1,181 @@
+package amatriciana
+
+//the kpk bitbase knows, for every king and pawn against king position, if the
+//side with the pawn wins. it's worked out backwards from the positions where the
+//pawn promotes safely or gets taken, until nothing changes anymore.
+//squares go from 0 (a1) to 63 (h8) and the pawn is always white and on the a-d files
+
+//24 pawn squares * 2 sides to move * 64 * 64 king squares
+const kpkSize = 24 * 2 * 64 * 64
+
+type kpkResult uint8
+
+const (
+	kpkInvalid kpkResult = 0
+	kpkUnknown kpkResult = 1
+	kpkDraw    kpkResult = 2
+	kpkWin     kpkResult = 4
+)
+
+//one bit per position, set if white wins
+var kpkBitbase [kpkSize / 64]uint64
+
+func init() {
+	db := make([]kpkResult, kpkSize)
+	for idx := range db {
+		db[idx] = kpkInitial(idx)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for idx, result := range db {
+			if result != kpkUnknown {
+				continue
+			}
+
+			db[idx] = kpkClassify(db, idx)
+			changed = changed || db[idx] != kpkUnknown
+		}
+	}
+
+	for idx, result := range db {
+		if result == kpkWin {
+			kpkBitbase[idx/64] |= 1 << uint(idx%64)
+		}
+	}
+}
+
+func kpkIndex(whiteToMove bool, whiteKing, blackKing, pawn int) int {
+	stm := 0
+	if !whiteToMove {
+		stm = 1
+	}
+	return whiteKing | blackKing<<6 | stm<<12 | (pawn%8)<<13 | (6-pawn/8)<<15
+}
+
+func kpkSquares(idx int) (whiteToMove bool, whiteKing, blackKing, pawn int) {
+	whiteKing = idx & 63
+	blackKing = (idx >> 6) & 63
+	whiteToMove = (idx>>12)&1 == 0
+	pawn = (6-(idx>>15)&7)*8 + (idx>>13)&3
+	return
+}
+
+func squareDistance(a, b int) int {
+	dx, dy := a%8-b%8, a/8-b/8
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+//the squares a king on square can go to
+func kingSquares(square int) []int {
+	squares := make([]int, 0, 8)
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := square%8+dx, square/8+dy
+			if (dx != 0 || dy != 0) && x >= 0 && x < 8 && y >= 0 && y < 8 {
+				squares = append(squares, y*8+x)
+			}
+		}
+	}
+	return squares
+}
+
+func whitePawnAttacks(pawn, square int) bool {
+	return square/8 == pawn/8+1 && (square%8 == pawn%8-1 || square%8 == pawn%8+1)
+}
+
+//what we know about a position without looking at its moves
+func kpkInitial(idx int) kpkResult {
+	whiteToMove, whiteKing, blackKing, pawn := kpkSquares(idx)
+
+	if squareDistance(whiteKing, blackKing) <= 1 || whiteKing == pawn || blackKing == pawn ||
+		(whiteToMove && whitePawnAttacks(pawn, blackKing)) {
+		return kpkInvalid
+	}
+
+	//the pawn promotes and the new queen can't be taken
+	promotion := pawn + 8
+	if whiteToMove && pawn/8 == 6 && whiteKing != promotion &&
+		(squareDistance(blackKing, promotion) > 1 || squareDistance(whiteKing, promotion) == 1) {
+		return kpkWin
+	}
+
+	if !whiteToMove {
+		//black is stalemated or takes the pawn
+		canMove := false
+		for _, square := range kingSquares(blackKing) {
+			if squareDistance(square, whiteKing) <= 1 {
+				continue
+			}
+			if square == pawn {
+				return kpkDraw
+			}
+			if !whitePawnAttacks(pawn, square) {
+				canMove = true
+			}
+		}
+		if !canMove {
+			return kpkDraw
+		}
+	}
+
+	return kpkUnknown
+}
+
+//white wins if one of its moves wins, black draws if one of its moves draws
+func kpkClassify(db []kpkResult, idx int) kpkResult {
+	whiteToMove, whiteKing, blackKing, pawn := kpkSquares(idx)
+
+	good, bad := kpkDraw, kpkWin
+	mover := blackKing
+	if whiteToMove {
+		good, bad = kpkWin, kpkDraw
+		mover = whiteKing
+	}
+
+	results := kpkInvalid
+	for _, square := range kingSquares(mover) {
+		if whiteToMove {
+			results |= db[kpkIndex(false, square, blackKing, pawn)]
+		} else {
+			results |= db[kpkIndex(true, whiteKing, square, pawn)]
+		}
+	}
+
+	if whiteToMove {
+		if pawn/8 < 6 {
+			results |= db[kpkIndex(false, whiteKing, blackKing, pawn+8)]
+		}
+		if pawn/8 == 1 && pawn+8 != whiteKing && pawn+8 != blackKing {
+			results |= db[kpkIndex(false, whiteKing, blackKing, pawn+16)]
+		}
+	}
+
+	if results&good != 0 {
+		return good
+	}
+	if results&kpkUnknown != 0 {
+		return kpkUnknown
+	}
+	return bad
+}
+
+//kpkProbe tells you if the side with the pawn wins. the squares are the
+//ones of the board, with the strong side's pawn going up
+func kpkProbe(strongToMove bool, strongKing, weakKing, pawn int) bool {
+	if pawn%8 > 3 {
+		strongKing, weakKing, pawn = strongKing^7, weakKing^7, pawn^7
+	}
+
+	idx := kpkIndex(strongToMove, strongKing, weakKing, pawn)
+	return kpkBitbase[idx/64]&(1<<uint(idx%64)) != 0
+}
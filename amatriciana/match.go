@@ -0,0 +1,319 @@
+package amatriciana
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//engine against engine: games between two players under a time control,
+//and what the results say about which one is stronger
+
+//TimeControl is how long the players can think: Base for the whole game
+//and Increment more after every move
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+}
+
+//ParseTimeControl reads a time control like 10+0.1, in seconds
+func ParseTimeControl(s string) (TimeControl, error) {
+	base, increment := s, "0"
+	if plus := strings.IndexByte(s, '+'); plus >= 0 {
+		base, increment = s[:plus], s[plus+1:]
+	}
+
+	var tc TimeControl
+	for _, field := range []struct {
+		text string
+		into *time.Duration
+	}{{base, &tc.Base}, {increment, &tc.Increment}} {
+		seconds, err := strconv.ParseFloat(field.text, 64)
+		if err != nil || seconds < 0 {
+			return TimeControl{}, fmt.Errorf("%q isn't a time control like 10+0.1", s)
+		}
+		*field.into = time.Duration(seconds * float64(time.Second))
+	}
+
+	if tc.Base <= 0 {
+		return TimeControl{}, errors.New("the time control needs some time to start with")
+	}
+	return tc, nil
+}
+
+//String writes the time control like the TimeControl tag of PGN
+func (tc TimeControl) String() string {
+	seconds := func(d time.Duration) string {
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	}
+	return seconds(tc.Base) + "+" + seconds(tc.Increment)
+}
+
+//Player is one side of a match, this engine with its own settings or
+//another engine somewhere else
+type Player interface {
+	Name() string
+	//NewGame tells the player that the next position is from another game
+	NewGame() error
+	//Move picks a move in uci notation for the position after the uci moves
+	//are played from start. clocks are the time white and black have left.
+	//the score is from the player's point of view, 0 if it didn't say one
+	Move(start Board, moves []string, clocks [2]time.Duration, tc TimeControl) (string, Score, error)
+}
+
+//EnginePlayer is this engine as a Player
+type EnginePlayer struct {
+	Label string
+	//Evaluator scores the positions, nil means the default weights
+	Evaluator *Evaluator
+	Threads   int
+	//Table gets cleared before every game
+	Table *TranspositionTable
+}
+
+//NewEnginePlayer makes a player with its own transposition table of hashSize megabytes
+func NewEnginePlayer(name string, evaluator *Evaluator, hashSize, threads int) *EnginePlayer {
+	return &EnginePlayer{name, evaluator, threads, NewTranspositionTable(hashSize)}
+}
+
+//Name is the player's label
+func (p *EnginePlayer) Name() string {
+	return p.Label
+}
+
+//NewGame clears the transposition table
+func (p *EnginePlayer) NewGame() error {
+	p.Table.Clear()
+	return nil
+}
+
+//Move searches the position for as long as the clock says, like with uci
+func (p *EnginePlayer) Move(start Board, moves []string, clocks [2]time.Duration, tc TimeControl) (string, Score, error) {
+	b := start.Clone()
+	for _, m := range moves {
+		if err := b.PerformMove(m); err != nil {
+			return "", 0, fmt.Errorf("couldn't play %s: %v", m, err)
+		}
+	}
+
+	lines := b.Search(SearchOptions{
+		MoveTime:  timeForMove(clocks[b.turn.index()], tc.Increment, 0),
+		Threads:   p.Threads,
+		Table:     p.Table,
+		Evaluator: p.Evaluator,
+	})
+	if len(lines) == 0 {
+		return "", 0, errors.New("there are no legal moves")
+	}
+
+	return lines[0].Move.UCIString(), lines[0].Score, nil
+}
+
+//PlayMatchGame plays a game between whitePlayer and blackPlayer starting from
+//the end of opening. the game comes back with its tags and result, and a few
+//words on how it ended. an error means the game couldn't be played, a player
+//that makes an illegal move or runs out of time just loses
+func PlayMatchGame(whitePlayer, blackPlayer Player, opening Game, tc TimeControl, adjudication Adjudication) (Game, string, error) {
+	game := Game{
+		Tags: map[string]string{
+			"White":       whitePlayer.Name(),
+			"Black":       blackPlayer.Name(),
+			"TimeControl": tc.String(),
+		},
+		Start: opening.Start.Clone(),
+		Moves: make([]move, 0, len(opening.Moves)+80),
+	}
+
+	for _, player := range []Player{whitePlayer, blackPlayer} {
+		if err := player.NewGame(); err != nil {
+			return game, "", fmt.Errorf("%s: %v", player.Name(), err)
+		}
+	}
+
+	b := opening.Start.Clone()
+	seen := map[uint64]int{b.Hash(): 1}
+	uciMoves := make([]string, 0, len(opening.Moves)+80)
+	for _, m := range opening.Moves {
+		uciMoves = append(uciMoves, m.UCIString())
+		game.Moves = append(game.Moves, m)
+		b.move(m)
+		seen[b.Hash()]++
+	}
+
+	finish := func(result, termination, reason string) (Game, string, error) {
+		game.Result = result
+		game.Tags["Result"] = result
+		game.Tags["Termination"] = termination
+		return game, reason, nil
+	}
+
+	players := [2]Player{whitePlayer, blackPlayer}
+	clocks := [2]time.Duration{tc.Base, tc.Base}
+	adjudicator := adjudicator{Adjudication: adjudication}
+
+	for ply := 0; ; ply++ {
+		if result, over := b.gameResult(seen); over {
+			return finish(result, "normal", b.endReason(seen))
+		}
+		if result, over := adjudicator.tablebase(b); over {
+			return finish(result, "adjudication", "tablebase")
+		}
+
+		us := b.turn.index()
+		player := players[us]
+		//whoever can't go on loses
+		loss := "0-1"
+		if b.turn == black {
+			loss = "1-0"
+		}
+
+		started := time.Now()
+		uciMove, score, err := player.Move(game.Start, uciMoves, clocks, tc)
+		clocks[us] -= time.Since(started)
+		if err != nil {
+			return game, "", fmt.Errorf("%s: %v", player.Name(), err)
+		}
+		if clocks[us] < 0 {
+			return finish(loss, "time forfeit", player.Name()+" lost on time")
+		}
+		clocks[us] += tc.Increment
+
+		m, err := b.parseMove(uciMove)
+		if err != nil || m.color != b.turn || !containsMove(b.moves(b.turn), m) {
+			return finish(loss, "rules infraction", fmt.Sprintf("%s played the illegal move %s", player.Name(), uciMove))
+		}
+
+		uciMoves = append(uciMoves, uciMove)
+		game.Moves = append(game.Moves, m)
+		b.move(m)
+		seen[b.Hash()]++
+
+		if b.turn == white {
+			score = -score
+		}
+		if result, over := adjudicator.update(ply, score); over {
+			reason := "resignation"
+			if result == "1/2-1/2" {
+				reason = "draw by agreement"
+			}
+			return finish(result, "adjudication", reason)
+		}
+	}
+}
+
+//endReason says why gameResult ended the game
+func (b Board) endReason(seen map[uint64]int) string {
+	switch {
+	case len(b.moves(b.turn)) == 0 && b.isKingInCheck(b.turn):
+		return "checkmate"
+	case len(b.moves(b.turn)) == 0:
+		return "stalemate"
+	case b.insufficientMaterial():
+		return "insufficient material"
+	case seen[b.Hash()] >= 3:
+		return "threefold repetition"
+	}
+	return "fifty move rule"
+}
+
+//MatchScore counts the results of a match from the first player's point of view
+type MatchScore struct {
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+//Add counts the result of a game, firstIsWhite says which side the first player had
+func (s *MatchScore) Add(result string, firstIsWhite bool) {
+	switch {
+	case result == "1/2-1/2":
+		s.Draws++
+	case (result == "1-0") == firstIsWhite:
+		s.Wins++
+	default:
+		s.Losses++
+	}
+}
+
+//Games is how many games have been counted
+func (s MatchScore) Games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+//the average points per game and how much they vary from game to game
+func (s MatchScore) meanAndVariance() (float64, float64) {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0.5, 0
+	}
+
+	mean := (float64(s.Wins) + float64(s.Draws)/2) / n
+	variance := (float64(s.Wins)*(1-mean)*(1-mean) +
+		float64(s.Draws)*(0.5-mean)*(0.5-mean) +
+		float64(s.Losses)*mean*mean) / n
+	return mean, variance
+}
+
+//the elo difference that gives score as the expected points per game
+func eloFromScore(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
+
+func scoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+//Elo is how much stronger the first player is, with the margin of a 95%
+//confidence interval around it. with only wins or only losses they're infinite
+func (s MatchScore) Elo() (float64, float64) {
+	mean, variance := s.meanAndVariance()
+	if mean <= 0 || mean >= 1 {
+		return eloFromScore(mean), math.Inf(1)
+	}
+	deviation := math.Sqrt(variance / float64(s.Games()))
+
+	low := eloFromScore(math.Max(mean-1.96*deviation, 0))
+	high := eloFromScore(math.Min(mean+1.96*deviation, 1))
+	return eloFromScore(mean), (high - low) / 2
+}
+
+//SPRT is a sequential probability ratio test: the match goes on until the
+//results say the elo difference is Elo0 (H0) or Elo1 (H1), getting it wrong
+//with a probability of Alpha when it's H0 and of Beta when it's H1
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+//Bounds are the log likelihood ratios that accept H0 and H1
+func (t SPRT) Bounds() (float64, float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+//LLR is the log likelihood ratio of H1 against H0, with the normal
+//approximation of the points per game
+func (t SPRT) LLR(s MatchScore) float64 {
+	mean, variance := s.meanAndVariance()
+	if variance == 0 {
+		return 0
+	}
+
+	s0, s1 := scoreFromElo(t.Elo0), scoreFromElo(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+//Verdict is "H0" or "H1" once one of them is accepted, and empty until then
+func (t SPRT) Verdict(s MatchScore) string {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return "H1"
+	case llr <= lower:
+		return "H0"
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package amatriciana
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tc, err := ParseTimeControl("10+0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.Base != 10*time.Second || tc.Increment != 100*time.Millisecond {
+		t.Errorf("expected 10s+100ms, got %v+%v", tc.Base, tc.Increment)
+	}
+	if tc.String() != "10+0.1" {
+		t.Errorf("expected 10+0.1, got %s", tc)
+	}
+
+	for _, s := range []string{"", "0+1", "ten", "10+x", "-5"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	var s MatchScore
+	for i := 0; i < 30; i++ {
+		s.Add("1-0", true)
+		s.Add("1-0", false)
+		s.Add("1/2-1/2", i%2 == 0)
+	}
+	for i := 0; i < 10; i++ {
+		s.Add("0-1", false)
+	}
+	if s.Wins != 40 || s.Losses != 30 || s.Draws != 30 {
+		t.Fatalf("expected 40 wins, 30 losses and 30 draws, got %+v", s)
+	}
+
+	elo, margin := s.Elo()
+	if math.Abs(elo-34.86) > 0.01 {
+		t.Errorf("expected about 34.86 elo, got %.2f", elo)
+	}
+	if margin < 40 || margin > 80 {
+		t.Errorf("a hundred games should give a margin of about 60 elo, got %.2f", margin)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	test := SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	lower, upper := test.Bounds()
+	if math.Abs(lower+2.94) > 0.01 || math.Abs(upper-2.94) > 0.01 {
+		t.Errorf("expected bounds of -2.94 and 2.94, got %.2f and %.2f", lower, upper)
+	}
+
+	if verdict := test.Verdict(MatchScore{Wins: 10, Losses: 9, Draws: 20}); verdict != "" {
+		t.Errorf("39 games shouldn't be enough, got %s", verdict)
+	}
+	if verdict := test.Verdict(MatchScore{Wins: 600, Losses: 400, Draws: 1000}); verdict != "H1" {
+		t.Errorf("expected H1 to be accepted, got %q", verdict)
+	}
+	if verdict := test.Verdict(MatchScore{Wins: 400, Losses: 600, Draws: 1000}); verdict != "H0" {
+		t.Errorf("expected H0 to be accepted, got %q", verdict)
+	}
+}
+
+//a player that always plays the same thing
+type stubbornPlayer string
+
+func (p stubbornPlayer) Name() string   { return "stubborn" }
+func (p stubbornPlayer) NewGame() error { return nil }
+func (p stubbornPlayer) Move(start Board, moves []string, clocks [2]time.Duration, tc TimeControl) (string, Score, error) {
+	return string(p), 0, nil
+}
+
+func TestPlayMatchGame(t *testing.T) {
+	start, _ := BoardFromFEN("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	opening := Game{Start: start}
+	tc := TimeControl{5 * time.Second, 0}
+
+	engine := NewEnginePlayer("engine", nil, 1, 1)
+	game, reason, err := PlayMatchGame(engine, stubbornPlayer("h7h6"), opening, tc, DefaultAdjudication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Result != "1-0" || reason != "checkmate" || len(game.Moves) != 1 {
+		t.Errorf("expected mate in one, got %s by %s after %d moves", game.Result, reason, len(game.Moves))
+	}
+	if game.Tags["White"] != "engine" || game.Tags["TimeControl"] != "5+0" {
+		t.Errorf("unexpected tags %v", game.Tags)
+	}
+
+	game, reason, err = PlayMatchGame(stubbornPlayer("e2e5"), engine, Game{Start: NewBoard()}, tc, DefaultAdjudication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Result != "0-1" || game.Tags["Termination"] != "rules infraction" {
+		t.Errorf("an illegal move should lose, got %s by %s", game.Result, reason)
+	}
+
+	//the pawn gets to e8 all right, it just can't become a king
+	promotion, _ := BoardFromFEN("8/4P3/8/8/8/k7/8/K7 w - - 0 1")
+	game, reason, err = PlayMatchGame(stubbornPlayer("e7e8k"), engine, Game{Start: promotion}, tc, DefaultAdjudication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Result != "0-1" || game.Tags["Termination"] != "rules infraction" || len(game.Moves) != 0 {
+		t.Errorf("promoting to a king should lose, got %s by %s after %d moves", game.Result, reason, len(game.Moves))
+	}
+}
@@ -0,0 +1,137 @@
+package amatriciana
+
+//MateOptions changes the way SolveMate looks for a mate
+type MateOptions struct {
+	//ChecksOnly makes the attacker only try moves that give check.
+	//it's a lot faster, but quiet keys get missed so a failed search
+	//doesn't prove that there's no mate
+	ChecksOnly bool
+}
+
+//MateSolution is what SolveMate found out about a position
+type MateSolution struct {
+	Moves int
+	Found bool
+	//every first move that forces mate in Moves moves or less.
+	//a sound problem has exactly one
+	Keys  []move
+	Nodes int
+}
+
+//Cooked tells you if the problem has more than one solution
+func (s MateSolution) Cooked() bool {
+	return len(s.Keys) > 1
+}
+
+type mateSolver struct {
+	checksOnly bool
+	nodes      int
+	proven     map[mateKey]bool
+}
+
+//SolveMate proves or refutes a forced mate in n moves for the side to move.
+//every defence is tried, so if Found is true the mate is forced
+func (b Board) SolveMate(n int) MateSolution {
+	return b.SolveMateWithOptions(n, MateOptions{})
+}
+
+//SolveMateWithOptions is SolveMate with some knobs to turn
+func (b Board) SolveMateWithOptions(n int, opts MateOptions) MateSolution {
+	solver := mateSolver{
+		checksOnly: opts.ChecksOnly,
+		proven:     make(map[mateKey]bool),
+	}
+
+	solution := MateSolution{Moves: n, Keys: make([]move, 0)}
+	if n < 1 {
+		return solution
+	}
+
+	//unlike the rest of the tree, at the root we keep going after
+	//the first key to find cooks
+	for _, key := range solver.attackerMoves(b, n) {
+		if solver.forcesMate(b, key, n) {
+			solution.Keys = append(solution.Keys, key)
+		}
+	}
+
+	solution.Found = len(solution.Keys) > 0
+	solution.Nodes = solver.nodes
+	return solution
+}
+
+//can the side to move mate in n moves or less?
+func (s *mateSolver) attackerWins(b Board, n int) bool {
+	key := mateKey{b.Hash(), n}
+	if result, alreadyProven := s.proven[key]; alreadyProven {
+		return result
+	}
+
+	result := false
+	for _, m := range s.attackerMoves(b, n) {
+		if s.forcesMate(b, m, n) {
+			result = true
+			break
+		}
+	}
+
+	s.proven[key] = result
+	return result
+}
+
+//does playing m lead to mate in n moves or less whatever the defender does?
+func (s *mateSolver) forcesMate(b Board, m move, n int) bool {
+	s.nodes++
+
+	afterKey := b.Clone()
+	afterKey.move(m)
+
+	defences := afterKey.moves(afterKey.turn)
+	if len(defences) == 0 {
+		//either it's mate or we just stalemated them
+		return afterKey.isKingInCheck(afterKey.turn)
+	}
+
+	if n == 1 {
+		return false
+	}
+
+	for _, defence := range defences {
+		afterDefence := afterKey.Clone()
+		afterDefence.move(defence)
+
+		if !s.attackerWins(afterDefence, n-1) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *mateSolver) attackerMoves(b Board, n int) []move {
+	moves := b.moves(b.turn)
+
+	//a move that mates is always a check, so on the last move
+	//we can skip the quiet ones without losing anything
+	if !s.checksOnly && n > 1 {
+		return moves
+	}
+
+	checks := make([]move, 0, len(moves))
+	for _, m := range moves {
+		afterMove := b.Clone()
+		afterMove.move(m)
+		if afterMove.isKingInCheck(afterMove.turn) {
+			checks = append(checks, m)
+		}
+	}
+
+	return checks
+}
+
+//a position and how many moves the attacker has left in it. the hash
+//leaves the move counters out, they don't matter for a mate
+type mateKey struct {
+	hash  uint64
+	moves int
+}
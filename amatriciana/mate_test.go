@@ -0,0 +1,94 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestMateInOne(t *testing.T) {
+	board, err := BoardFromFEN("7k/8/6K1/8/8/8/8/R7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution := board.SolveMate(1)
+	if !solution.Found || solution.Cooked() {
+		t.Fatal("expected exactly one key, got", len(solution.Keys))
+	}
+
+	if solution.Keys[0].UCIString() != "a1a8" {
+		t.Error("wrong key:", solution.Keys[0].UCIString())
+	}
+}
+
+func TestMateInTwo(t *testing.T) {
+	board, err := BoardFromFEN("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if board.SolveMate(1).Found {
+		t.Error("there shouldn't be a mate in one")
+	}
+
+	solution := board.SolveMate(2)
+	if !solution.Found {
+		t.Fatal("couldn't find the mate in two")
+	}
+
+	foundKey := false
+	for _, key := range solution.Keys {
+		if key.UCIString() == "c6b6" {
+			foundKey = true
+		}
+	}
+	if !foundKey {
+		t.Error("Kb6 should be a key")
+	}
+
+	checksOnly := board.SolveMateWithOptions(2, MateOptions{ChecksOnly: true})
+	if checksOnly.Found {
+		t.Error("the key is quiet, a checks only search shouldn't find it")
+	}
+}
+
+func TestStalemateIsNotMate(t *testing.T) {
+	board, err := BoardFromFEN("k7/8/1Q6/8/8/8/8/7K b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !board.IsStalemate() || board.IsCheckmate() {
+		t.Error("black should be stalemated")
+	}
+
+	//with the king that far away there's no mate in one: Qb7+ gets taken
+	//and Qc7 only stalemates
+	board, err = BoardFromFEN("k7/8/8/1Q6/8/8/8/1K6 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if solution := board.SolveMate(1); solution.Found || len(solution.Keys) != 0 {
+		t.Error("there's no mate in one, but it found", solution.Keys)
+	}
+}
+
+func TestPromotionMoves(t *testing.T) {
+	board, err := BoardFromFEN("8/4P3/8/8/8/k7/8/K7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promotions := make(map[pieceType]bool)
+	for _, m := range board.moves(white) {
+		if m.moveType == promotion {
+			promotions[m.promotesInto] = true
+		}
+	}
+
+	for _, pt := range [...]pieceType{queen, rook, bishop, knight} {
+		if !promotions[pt] {
+			t.Error("missing promotion into", pt.String())
+		}
+	}
+}
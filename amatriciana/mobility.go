@@ -0,0 +1,124 @@
+package amatriciana
+
+import "math/bits"
+
+//how active the pieces are: how many safe squares they reach, outposts,
+//rooks on the seventh and pieces that got themselves trapped
+
+//Mobility is the piece activity part of the evaluation, from white's point of view
+func (b Board) Mobility() Score {
+	return Score(whiteMinusBlack(defaultEvaluator.mobility(b, b.attackMap())).taper(b.phase))
+}
+
+//by color
+func (e *Evaluator) mobility(b Board, m attackMap) [2]taperedScore {
+	var score [2]taperedScore
+
+	for i, p := range b.pieces {
+		if p.pieceType == pawn || p.pieceType == king {
+			continue
+		}
+
+		score[p.color.index()].add(e.pieceActivity(b, p, m.byPiece[i], m))
+	}
+
+	return score
+}
+
+func (e *Evaluator) pieceActivity(b Board, p piece, attacks uint64, m attackMap) taperedScore {
+	params := &e.params
+	us, them := p.color.index(), (!p.color).index()
+
+	//squares enemy pawns cover aren't worth going to
+	safe := attacks &^ m.byColor[us] &^ m.byType[them][pawn]
+	squares := bits.OnesCount64(safe) - params.UsualMobility[p.pieceType]
+	weight := params.Mobility[p.pieceType]
+	score := taperedScore{weight.Mg * squares, weight.Eg * squares}
+
+	rank := p.position.y
+	ahead := 1
+	if p.color == black {
+		rank = 9 - rank
+		ahead = -1
+	}
+
+	switch p.pieceType {
+	case knight, bishop:
+		if rank >= 4 && rank <= 6 && m.byType[us][pawn]&p.position.bit() != 0 && !b.canBeChased(p, m) {
+			if p.pieceType == knight {
+				score.add(params.KnightOutpost.score())
+			} else {
+				score.add(params.BishopOutpost.score())
+			}
+		}
+
+		//a bishop on a7 that the pawn on b6 shuts in
+		if p.pieceType == bishop && rank == 7 && (p.position.x == 1 || p.position.x == 8) {
+			blocker := xy{2, p.position.y - ahead}
+			if p.position.x == 8 {
+				blocker.x = 7
+			}
+			if m.pieces[them][pawn]&blocker.bit() != 0 {
+				score.add(params.TrappedBishop.score())
+			}
+		}
+
+	case rook:
+		theirKingOnEighth := m.pieces[them][king]&rankMask(p.color, 8) != 0
+		if rank == 7 && (theirKingOnEighth || m.pieces[them][pawn]&rankMask(p.color, 7) != 0) {
+			score.add(params.RookOnSeventh.score())
+		}
+
+		//a rook stuck in the corner by a king that can't castle anymore
+		kingPos, _ := b.kingPosition(p.color)
+		canCastle := b.whiteCanCastle
+		if p.color == black {
+			canCastle = b.blackCanCastle
+		}
+		if rank == 1 && kingPos.y == p.position.y && !canCastle[0] && !canCastle[1] && squares+params.UsualMobility[rook] <= 3 &&
+			((kingPos.x > 4 && p.position.x > kingPos.x) || (kingPos.x < 5 && p.position.x < kingPos.x)) {
+			score.add(params.TrappedRook.score())
+		}
+	}
+
+	return score
+}
+
+//if an enemy pawn can ever come up and attack the piece
+func (b Board) canBeChased(p piece, m attackMap) bool {
+	them := (!p.color).index()
+
+	for _, file := range []int{p.position.x - 1, p.position.x + 1} {
+		if file < 1 || file > 8 {
+			continue
+		}
+
+		for y := 1; y <= 8; y++ {
+			inFront := (p.color == white && y > p.position.y) || (p.color == black && y < p.position.y)
+			if inFront && m.pieces[them][pawn]&(xy{file, y}).bit() != 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//the relative rank of col, from 1 to 8, as a bitboard
+func rankMask(col color, rank int) uint64 {
+	if col == black {
+		rank = 9 - rank
+	}
+	return uint64(0xff) << uint(8*(rank-1))
+}
+
+//how many pieces of col attack square
+func (b Board) attackersCount(m attackMap, square xy, col color) int {
+	count := 0
+	for i, p := range b.pieces {
+		if p.color == col && m.byPiece[i]&square.bit() != 0 {
+			count++
+		}
+	}
+	return count
+}
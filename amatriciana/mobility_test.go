@@ -0,0 +1,67 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func activityOf(t *testing.T, fen, square string) taperedScore {
+	board, err := BoardFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position, _ := parsexy(square)
+
+	m := board.attackMap()
+	for i, p := range board.pieces {
+		if p.position == position {
+			return defaultEvaluator.pieceActivity(board, p, m.byPiece[i], m)
+		}
+	}
+
+	t.Fatal("no piece on", square)
+	return taperedScore{}
+}
+
+func TestMobility(t *testing.T) {
+	center := activityOf(t, "4k3/8/8/8/4N3/8/8/4K3 w - - 0 1", "e4")
+	corner := activityOf(t, "4k3/8/8/8/8/8/8/N3K3 w - - 0 1", "a1")
+	if center.mg <= corner.mg {
+		t.Error("a knight in the middle should be more active than one in the corner")
+	}
+
+	//the squares black's pawns cover don't count
+	covered := activityOf(t, "4k3/8/2p2p2/8/4N3/8/8/4K3 w - - 0 1", "e4")
+	if covered.mg >= center.mg {
+		t.Error("squares covered by pawns shouldn't count")
+	}
+}
+
+func TestOutposts(t *testing.T) {
+	outpost := activityOf(t, "4k3/8/8/4N3/3P4/8/8/4K3 w - - 0 1", "e5")
+	chased := activityOf(t, "4k3/5p2/8/4N3/3P4/8/8/4K3 w - - 0 1", "e5")
+	if outpost.mg-chased.mg < defaultEvaluator.params.KnightOutpost.Mg {
+		t.Errorf("the knight should only be on an outpost without the f pawn: %v, %v", outpost, chased)
+	}
+}
+
+func TestRookOnSeventh(t *testing.T) {
+	seventh := activityOf(t, "4k3/R7/8/8/8/8/8/4K3 w - - 0 1", "a7")
+	sixth := activityOf(t, "4k3/8/R7/8/8/8/8/4K3 w - - 0 1", "a6")
+	if seventh.eg-sixth.eg < defaultEvaluator.params.RookOnSeventh.Eg {
+		t.Errorf("the rook should get the bonus on the seventh: %v, %v", seventh, sixth)
+	}
+}
+
+func TestTrappedPieces(t *testing.T) {
+	trapped := activityOf(t, "4k3/B7/1p6/8/8/8/8/4K3 w - - 0 1", "a7")
+	free := activityOf(t, "4k3/B7/8/8/8/8/8/4K3 w - - 0 1", "a7")
+	if trapped.mg-free.mg > defaultEvaluator.params.TrappedBishop.Mg {
+		t.Errorf("the bishop should be trapped: %v, %v", trapped, free)
+	}
+
+	trapped = activityOf(t, "4k3/8/8/8/8/8/5PPP/5K1R w - - 0 1", "h1")
+	free = activityOf(t, "4k3/8/8/8/8/8/5PPP/4K2R w K - 0 1", "h1")
+	if trapped.mg >= free.mg {
+		t.Errorf("the rook should be trapped by the king that moved: %v, %v", trapped, free)
+	}
+}
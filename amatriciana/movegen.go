@@ -0,0 +1,222 @@
+package amatriciana
+
+import "math/bits"
+
+//legal move generation straight from the bitboards: the checkers say where
+//the other pieces have to go, the pins say which lines the pinned pieces
+//can't leave, and the king only goes where nothing attacks it. en passant
+//is the only move that still gets tried out, and only on the bitboards
+
+//which moves the generator gives back. captures and quiet moves together
+//are all the moves, a promotion without a capture is a quiet move
+type moveKind int
+
+const (
+	allMoves moveKind = iota
+	captureMoves
+	quietMoves
+)
+
+//captures are the legal moves of col that take something, en passant included
+func (b Board) captures(col color) []move {
+	return b.legalMoves(col, captureMoves)
+}
+
+//quiets are the legal moves of col that don't take anything, castling included
+func (b Board) quiets(col color) []move {
+	return b.legalMoves(col, quietMoves)
+}
+
+func (b Board) legalMoves(col color, kind moveKind) []move {
+	m := b.bitboards()
+	us, them := m.byColor[col.index()], m.byColor[(!col).index()]
+	moves := make([]move, 0, 48)
+
+	//where the moves can land
+	var targets uint64
+	switch kind {
+	case allMoves:
+		targets = ^us
+	case captureMoves:
+		targets = them
+	case quietMoves:
+		targets = ^m.occupied
+	}
+
+	kingSquare, hasKing := m.kingSquare(col)
+	var checkers uint64
+	var pinRays [64]uint64
+	if hasKing {
+		checkers = m.attackersOf(kingSquare, !col, m.occupied)
+		for _, x := range m.blockers(kingSquare, !col) {
+			if us&x.Blocker.bit() != 0 {
+				pinRays[x.Blocker] = between[kingSquare][x.Attacker] | x.Attacker.bit()
+			}
+		}
+	}
+
+	//in check everything but the king has to take the checker or get in
+	//its way, and in double check only the king can do something
+	evasions := ^uint64(0)
+	switch bits.OnesCount64(checkers) {
+	case 0:
+	case 1:
+		checker := bits.TrailingZeros64(checkers)
+		evasions = checkers | between[kingSquare][checker]
+	default:
+		evasions = 0
+	}
+
+	for _, p := range b.pieces {
+		if p.color != col {
+			continue
+		}
+
+		from := p.position.index()
+		allowed := targets & evasions
+		if pinRays[from] != 0 {
+			allowed &= pinRays[from]
+		}
+
+		switch p.pieceType {
+		case king:
+			moves = b.legalKingMoves(moves, m, p, targets, checkers != 0, kind != captureMoves)
+		case pawn:
+			moves = b.legalPawnMoves(moves, m, p, allowed, kind)
+		default:
+			for to := pieceAttacks(p, m.occupied) & allowed; to != 0; to &= to - 1 {
+				moves = append(moves, move{p.pieceType, col, p.position, squareXY(bits.TrailingZeros64(to)), normalMove, pawn})
+			}
+		}
+	}
+
+	return moves
+}
+
+func (b Board) legalKingMoves(moves []move, m attackMap, p piece, targets uint64, inCheck, castling bool) []move {
+	from := p.position.index()
+	//the king can't hide behind itself from a slider
+	withoutKing := m.occupied &^ p.position.bit()
+
+	for to := kingAttacks[from] & targets; to != 0; to &= to - 1 {
+		square := bits.TrailingZeros64(to)
+		if m.attackersOf(square, !p.color, withoutKing) == 0 {
+			moves = append(moves, move{king, p.color, p.position, squareXY(square), normalMove, pawn})
+		}
+	}
+
+	if !castling || inCheck {
+		return moves
+	}
+
+	canCastle := b.whiteCanCastle
+	if p.color == black {
+		canCastle = b.blackCanCastle
+	}
+
+	//the squares that have to be empty and the ones the king walks over
+	sides := [2]struct {
+		empty, safe []xy
+		moveType
+	}{
+		{[]xy{{1, 0}, {2, 0}}, []xy{{1, 0}, {2, 0}}, shortCastle},
+		{[]xy{{-1, 0}, {-2, 0}, {-3, 0}}, []xy{{-1, 0}, {-2, 0}}, longCastle},
+	}
+	for i, side := range sides {
+		if !canCastle[i] {
+			continue
+		}
+
+		possible := true
+		for _, step := range side.empty {
+			square := p.position.plus(step)
+			possible = possible && isInBounds(square) && m.occupied&square.bit() == 0
+		}
+		for _, step := range side.safe {
+			possible = possible && m.attackersOf(p.position.plus(step).index(), !p.color, m.occupied) == 0
+		}
+
+		if possible {
+			moves = append(moves, move{king, p.color, p.position, p.position.plus(side.safe[1]), side.moveType, pawn})
+		}
+	}
+
+	return moves
+}
+
+func (b Board) legalPawnMoves(moves []move, m attackMap, p piece, allowed uint64, kind moveKind) []move {
+	from := p.position.index()
+	forward := 8
+	if p.color == black {
+		forward = -8
+	}
+	them := m.byColor[(!p.color).index()]
+
+	if kind != quietMoves {
+		for to := pawnAttacks[p.color.index()][from] & them & allowed; to != 0; to &= to - 1 {
+			moves = appendPawnMove(moves, move{pawn, p.color, p.position, squareXY(bits.TrailingZeros64(to)), normalMove, pawn})
+		}
+
+		if b.enPassant != (xy{}) && pawnAttacks[p.color.index()][from]&b.enPassant.bit() != 0 && b.isLegalEnPassant(m, p) {
+			moves = append(moves, move{pawn, p.color, p.position, b.enPassant, enPassant, pawn})
+		}
+	}
+
+	if kind != captureMoves {
+		//the pawn can't already be on the last rank, so one step is always on the board
+		single := from + forward
+		if m.occupied&(1<<uint(single)) != 0 {
+			return moves
+		}
+		if allowed&(1<<uint(single)) != 0 {
+			moves = appendPawnMove(moves, move{pawn, p.color, p.position, squareXY(single), normalMove, pawn})
+		}
+
+		double := single + forward
+		startingRank := (p.color == white && p.position.y == 2) || (p.color == black && p.position.y == 7)
+		if startingRank && m.occupied&(1<<uint(double)) == 0 && allowed&(1<<uint(double)) != 0 {
+			moves = append(moves, move{pawn, p.color, p.position, squareXY(double), normalMove, pawn})
+		}
+	}
+
+	return moves
+}
+
+//en passant takes two pieces off a rank at once and the pawn it takes isn't
+//on the square the capture lands on, so neither the pins nor the checks
+//above get it right. it's easier to try it on the bitboards
+func (b Board) isLegalEnPassant(m attackMap, p piece) bool {
+	victim := xy{b.enPassant.x, p.position.y}
+	if m.pieces[(!p.color).index()][pawn]&victim.bit() == 0 {
+		return false
+	}
+
+	kingSquare, hasKing := m.kingSquare(p.color)
+	if !hasKing {
+		return true
+	}
+
+	occupied := m.occupied&^p.position.bit()&^victim.bit() | b.enPassant.bit()
+	return m.attackersOf(kingSquare, !p.color, occupied) == 0
+}
+
+//Perft counts the legal move sequences depth plies long from the position.
+//the counts of well known positions are the way to check a move generator
+func (b Board) Perft(depth int) int {
+	if depth <= 0 {
+		return 1
+	}
+
+	moves := b.moves(b.turn)
+	if depth == 1 {
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, m := range moves {
+		child := b.clonePosition()
+		child.move(m)
+		nodes += child.Perft(depth - 1)
+	}
+	return nodes
+}
@@ -0,0 +1,109 @@
+package amatriciana
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+//https://www.chessprogramming.org/Perft_Results
+var perftPositions = []struct {
+	fen   string
+	nodes []int
+}{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []int{20, 400, 8902, 197281}},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+	{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812, 43238}},
+	{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467}},
+	{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379}},
+	{"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []int{46, 2079, 89890}},
+	//en passant that would leave the king in check on the rank
+	{"8/8/8/K2pP2r/8/8/8/7k w - d6 0 1", []int{6}},
+	//en passant takes the pawn that just gave check
+	{"8/8/8/5k2/3pP3/8/8/4K3 b - e3 0 1", []int{9}},
+	//double check, only the king moves
+	{"4k3/8/8/8/1b6/8/4r3/R2NK3 w - - 0 1", []int{2}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, position := range perftPositions {
+		board := boardOrFail(t, position.fen)
+		for depth, expected := range position.nodes {
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			if nodes := board.Perft(depth + 1); nodes != expected {
+				t.Errorf("%s: perft %d should be %d, not %d", position.fen, depth+1, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestCapturesAndQuiets(t *testing.T) {
+	for _, position := range perftPositions {
+		board := boardOrFail(t, position.fen)
+
+		captures, quiets := board.captures(board.turn), board.quiets(board.turn)
+		if len(captures)+len(quiets) != len(board.moves(board.turn)) {
+			t.Errorf("%s: %d captures and %d quiet moves don't add up to %d moves",
+				position.fen, len(captures), len(quiets), len(board.moves(board.turn)))
+		}
+		for _, m := range captures {
+			if !board.isCapture(m) {
+				t.Errorf("%s: %s isn't a capture", position.fen, m.UCIString())
+			}
+		}
+		for _, m := range quiets {
+			if board.isCapture(m) {
+				t.Errorf("%s: %s is a capture", position.fen, m.UCIString())
+			}
+		}
+	}
+}
+
+//the generator against trying every move on the board, over a few random games
+func TestLegalMovesMatchMakeAndTest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for game := 0; game < 20; game++ {
+		board := NewBoard()
+		for ply := 0; ply < 200; ply++ {
+			fast := uciStrings(board.moves(board.turn))
+
+			var slow []move
+			for _, p := range board.piecesOfColor(board.turn) {
+				slow = append(slow, p.moves(board)...)
+			}
+
+			if expected := uciStrings(slow); !equalStrings(fast, expected) {
+				t.Fatalf("%s: the generator gives %v instead of %v", board.FEN(), fast, expected)
+			}
+
+			moves := board.moves(board.turn)
+			if len(moves) == 0 {
+				break
+			}
+			board.move(moves[random.Intn(len(moves))])
+		}
+	}
+}
+
+func uciStrings(moves []move) []string {
+	strings := make([]string, len(moves))
+	for i, m := range moves {
+		strings[i] = m.UCIString()
+	}
+	sort.Strings(strings)
+	return strings
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -72,10 +72,14 @@ func (b Board) parseMove(inputStr string) (move, error) {
 		moveType = shortCastle
 	}
 
+	if piece.pieceType == pawn && to == b.enPassant && from.x != to.x {
+		moveType = enPassant
+	}
+
 	outputMove := move{piece.pieceType, piece.color, from, to, moveType, pawn}
 
 	if piece.pieceType == pawn && (to.y == 8 || to.y == 1) {
-		moveType = promotion
+		outputMove.moveType = promotion
 		if len(input) < 5 {
 			return move{}, fmt.Errorf("pawn promotes to an unknown piece")
 		}
@@ -84,6 +88,9 @@ func (b Board) parseMove(inputStr string) (move, error) {
 		if err != nil {
 			return outputMove, err
 		}
+		if promotesInto == pawn || promotesInto == king {
+			return move{}, fmt.Errorf("a pawn can't promote to a %s", promotesInto.String())
+		}
 
 		outputMove.promotesInto = promotesInto
 	}
@@ -159,12 +166,25 @@ func parsexy(inputStr string) (xy, error) {
 
 //performs a move. it doesn't check if the move is legal
 func (b *Board) move(m move) bool {
+	//captures and pawn moves set it back to zero further down
+	b.halfMoves++
+	if b.turn == black {
+		b.moveNumber++
+	}
+
+	captured := m.to
+	if m.moveType == enPassant {
+		captured = xy{m.to.x, m.from.y}
+	}
+
 	for i, piece := range b.pieces {
 		//if the move is a capture, remove the captured piece
 		//and also reset the halfMoves field
+		if piece.position == captured {
+			b.psq[piece.color.index()].sub(pieceSquare(piece))
+			b.phase -= phaseWeight[piece.pieceType]
+			b.nnue.remove(piece)
 
-		//TODO: this just straight up crashes
-		if piece.position == m.to {
 			b.pieces[len(b.pieces)-1], b.pieces[i] = b.pieces[i], b.pieces[len(b.pieces)-1]
 			b.pieces = b.pieces[:len(b.pieces)-1]
 
@@ -184,29 +204,77 @@ func (b *Board) move(m move) bool {
 				b.halfMoves = 0
 				if m.moveType == promotion {
 					b.pieces[i].pieceType = m.promotesInto
+					b.phase += phaseWeight[m.promotesInto]
 				}
 			}
+
+			b.psq[piece.color.index()].sub(pieceSquare(piece))
+			b.psq[piece.color.index()].add(pieceSquare(b.pieces[i]))
+			b.nnue.remove(piece)
+			b.nnue.add(b.pieces[i])
+			break
 		}
 	}
 
+	//the rook jumps over the king on the same rank the king is on
 	if m.moveType == longCastle {
-		rook, isThereAPiece := b.pointerPieceAtPosition(xy{1, 1})
-		if isThereAPiece {
-			rook.position = xy{4, 1}
-		}
+		b.relocate(xy{1, m.from.y}, xy{4, m.from.y})
 	}
-
 	if m.moveType == shortCastle {
-		rook, isThereAPiece := b.pointerPieceAtPosition(xy{8, 1})
-		if isThereAPiece {
-			rook.position = xy{6, 1}
-		}
+		b.relocate(xy{8, m.from.y}, xy{6, m.from.y})
+	}
+
+	b.enPassant = xy{}
+	if m.piece == pawn && (m.to.y-m.from.y == 2 || m.to.y-m.from.y == -2) {
+		b.enPassant = xy{m.from.x, (m.from.y + m.to.y) / 2}
 	}
 
+	b.updateCastlingRights(m)
+
 	b.turn = !b.turn
 	return true
 }
 
+//moves whatever is on from to to, without any other side effect
+func (b *Board) relocate(from, to xy) {
+	for i := range b.pieces {
+		if b.pieces[i].position == from {
+			col := b.pieces[i].color.index()
+			b.psq[col].sub(pieceSquare(b.pieces[i]))
+			b.nnue.remove(b.pieces[i])
+			b.pieces[i].position = to
+			b.psq[col].add(pieceSquare(b.pieces[i]))
+			b.nnue.add(b.pieces[i])
+			return
+		}
+	}
+}
+
+//a king that moves loses both castling rights, a rook that moves
+//or gets captured on its corner loses its own
+func (b *Board) updateCastlingRights(m move) {
+	if m.piece == king {
+		if m.color == white {
+			b.whiteCanCastle = [2]bool{false, false}
+		} else {
+			b.blackCanCastle = [2]bool{false, false}
+		}
+	}
+
+	for _, square := range [...]xy{m.from, m.to} {
+		switch square {
+		case xy{8, 1}:
+			b.whiteCanCastle[0] = false
+		case xy{1, 1}:
+			b.whiteCanCastle[1] = false
+		case xy{8, 8}:
+			b.blackCanCastle[0] = false
+		case xy{1, 8}:
+			b.blackCanCastle[1] = false
+		}
+	}
+}
+
 func (b *Board) testMove(m move) bool {
 	b.turn = !b.turn
 
@@ -215,6 +283,13 @@ func (b *Board) testMove(m move) bool {
 
 //Clone creates a Board that is identical to the input one
 func (b Board) Clone() Board {
+	newBoard := b.clonePosition()
+	newBoard.nnue = b.nnue.clone()
+	return newBoard
+}
+
+//Clone without the network's accumulator, for boards that won't get evaluated
+func (b Board) clonePosition() Board {
 	newBoard := Board{
 		pieces:         make([]piece, len(b.pieces)),
 		turn:           b.turn,
@@ -223,6 +298,8 @@ func (b Board) Clone() Board {
 		enPassant:      b.enPassant,
 		halfMoves:      b.halfMoves,
 		moveNumber:     b.moveNumber,
+		psq:            b.psq,
+		phase:          b.phase,
 	}
 
 	copy(newBoard.pieces, b.pieces)
@@ -244,7 +321,7 @@ func (b Board) eliminateIllegalMoves(moves []move) []move {
 //we just perform it and make sure the king isn't in check
 //is this performant? no. is it easy? no lmao
 func (b Board) isLegal(m move) bool {
-	dummyBoard := b.Clone()
+	dummyBoard := b.clonePosition()
 	dummyBoard.testMove(m)
 
 	if dummyBoard.isKingInCheck(dummyBoard.turn) {
@@ -268,3 +345,13 @@ func (b Board) isPossible(m move) bool {
 
 	return false
 }
+
+//captures are moves that land on a piece, plus en passant
+func (b Board) isCapture(m move) bool {
+	if m.moveType == enPassant {
+		return true
+	}
+
+	_, occupied := b.pieceAtPosition(m.to)
+	return occupied
+}
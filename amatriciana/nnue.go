@@ -0,0 +1,234 @@
+package amatriciana
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+//a small efficiently updatable neural network, the other way to evaluate.
+//the inputs are the 768 piece-square combinations (2 colors, 6 types, 64
+//squares) seen once from each side. the first layer only changes by a couple
+//of columns when a piece moves, so every board keeps its sums (the
+//accumulator) and move() updates them instead of starting over.
+//
+//network files are little endian:
+//
+//	"AMNN", uint32 version (1), uint32 hidden size H
+//	int16 feature weights, 768 times H, the weights of one input together
+//	int16 feature biases, H
+//	int16 output weights, 2H, the side to move's half first
+//	int32 output bias
+//
+//the inputs are counted from each side's point of view: the board is flipped
+//for black so its pieces go up the board, and the side's own pieces come first
+
+const (
+	networkMagic   = "AMNN"
+	networkVersion = 1
+	networkInputs  = 2 * 6 * 64
+
+	//the quantization: the activations go from 0 to networkQA, the output
+	//weights are multiplied by networkQB and the output by networkScale
+	networkQA    = 255
+	networkQB    = 64
+	networkScale = 400
+)
+
+//Network is a neural network that can evaluate positions
+type Network struct {
+	hidden         int
+	featureWeights []int16
+	featureBiases  []int16
+	outputWeights  []int16
+	outputBias     int32
+}
+
+//the sums of the first layer from each side's point of view, by color index
+type accumulator struct {
+	network *Network
+	values  [2][]int16
+}
+
+//LoadNetwork reads a network file
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadNetwork(bufio.NewReader(file))
+}
+
+//ReadNetwork reads a network in the format of network files
+func ReadNetwork(r io.Reader) (*Network, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Magic[:]) != networkMagic {
+		return nil, errors.New("not a network file")
+	}
+	if header.Version != networkVersion {
+		return nil, fmt.Errorf("network version %d isn't supported", header.Version)
+	}
+	if header.Hidden == 0 || header.Hidden > 1<<16 {
+		return nil, fmt.Errorf("a hidden layer of %d doesn't make sense", header.Hidden)
+	}
+
+	n := NewNetwork(int(header.Hidden))
+	for _, data := range []interface{}{n.featureWeights, n.featureBiases, n.outputWeights, &n.outputBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("the network file is too short: %v", err)
+		}
+	}
+
+	return n, nil
+}
+
+//NewNetwork makes a network with hidden neurons and all the weights at zero
+func NewNetwork(hidden int) *Network {
+	return &Network{
+		hidden:         hidden,
+		featureWeights: make([]int16, networkInputs*hidden),
+		featureBiases:  make([]int16, hidden),
+		outputWeights:  make([]int16, 2*hidden),
+	}
+}
+
+//Write writes the network in the format of network files
+func (n *Network) Write(w io.Writer) error {
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}{Version: networkVersion, Hidden: uint32(n.hidden)}
+	copy(header.Magic[:], networkMagic)
+
+	for _, data := range []interface{}{header, n.featureWeights, n.featureBiases, n.outputWeights, n.outputBias} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//the input a piece turns on from perspective's point of view
+func networkFeature(perspective color, p piece) int {
+	square := p.position.index()
+	if perspective == black {
+		square ^= 56
+	}
+
+	side := 0
+	if p.color != perspective {
+		side = 1
+	}
+
+	return side*384 + int(p.pieceType)*64 + square
+}
+
+func (n *Network) newAccumulator(b Board) *accumulator {
+	a := &accumulator{network: n}
+	for col := range a.values {
+		a.values[col] = make([]int16, n.hidden)
+		copy(a.values[col], n.featureBiases)
+	}
+
+	for _, p := range b.pieces {
+		a.add(p)
+	}
+	return a
+}
+
+func (a *accumulator) add(p piece) {
+	if a == nil {
+		return
+	}
+
+	hidden := a.network.hidden
+	for col, perspective := range [...]color{white, black} {
+		feature := networkFeature(perspective, p)
+		weights := a.network.featureWeights[feature*hidden : (feature+1)*hidden]
+		values := a.values[col]
+		for i := range values {
+			values[i] += weights[i]
+		}
+	}
+}
+
+func (a *accumulator) remove(p piece) {
+	if a == nil {
+		return
+	}
+
+	hidden := a.network.hidden
+	for col, perspective := range [...]color{white, black} {
+		feature := networkFeature(perspective, p)
+		weights := a.network.featureWeights[feature*hidden : (feature+1)*hidden]
+		values := a.values[col]
+		for i := range values {
+			values[i] -= weights[i]
+		}
+	}
+}
+
+func (a *accumulator) clone() *accumulator {
+	if a == nil {
+		return nil
+	}
+
+	c := &accumulator{network: a.network}
+	for col := range a.values {
+		c.values[col] = make([]int16, len(a.values[col]))
+		copy(c.values[col], a.values[col])
+	}
+	return c
+}
+
+//clipped relu
+func activation(x int16) int32 {
+	if x < 0 {
+		return 0
+	}
+	if x > networkQA {
+		return networkQA
+	}
+	return int32(x)
+}
+
+//evaluate is the network's score from white's point of view
+func (n *Network) evaluate(b Board) Score {
+	a := b.nnue
+	if a == nil || a.network != n {
+		a = n.newAccumulator(b)
+	}
+
+	us, them := a.values[b.turn.index()], a.values[(!b.turn).index()]
+	var sum int64
+	for i := 0; i < n.hidden; i++ {
+		sum += int64(activation(us[i]) * int32(n.outputWeights[i]))
+		sum += int64(activation(them[i]) * int32(n.outputWeights[n.hidden+i]))
+	}
+
+	score := Score((sum + int64(n.outputBias)) * networkScale / (networkQA * networkQB))
+	//whatever the network says, it can't know as much as a tablebase
+	if score >= tbWinScore {
+		score = tbWinScore - 1
+	} else if score <= -tbWinScore {
+		score = -tbWinScore + 1
+	}
+
+	if b.turn == black {
+		return -score
+	}
+	return score
+}
@@ -0,0 +1,118 @@
+package amatriciana
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomNetwork(hidden int, seed int64) *Network {
+	random := rand.New(rand.NewSource(seed))
+	n := NewNetwork(hidden)
+	for i := range n.featureWeights {
+		n.featureWeights[i] = int16(random.Intn(65) - 32)
+	}
+	for i := range n.featureBiases {
+		n.featureBiases[i] = int16(random.Intn(129) - 64)
+	}
+	for i := range n.outputWeights {
+		n.outputWeights[i] = int16(random.Intn(129) - 64)
+	}
+	n.outputBias = int32(random.Intn(2001) - 1000)
+	return n
+}
+
+//the accumulator move() keeps has to be the same as the one from scratch
+func TestIncrementalAccumulator(t *testing.T) {
+	network := randomNetwork(32, 1)
+	games := []struct {
+		fen   string
+		moves []string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []string{
+			"e2e4", "d7d5", "e4d5", "c7c5", "d5c6", "b7c6", "g1f3", "g8f6",
+			"f1e2", "e7e6", "e1g1", "f8e7", "d2d4", "e8g8",
+		}},
+		{"r3k3/1P6/8/8/8/8/6p1/R3K2N w Qq - 0 1", []string{"b7a8q", "e8e7", "e1c1", "g2h1n"}},
+	}
+
+	for _, game := range games {
+		board, err := BoardFromFEN(game.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board.nnue = network.newAccumulator(board)
+
+		for _, m := range game.moves {
+			if err := board.PerformMove(m); err != nil {
+				t.Fatal(m, err)
+			}
+
+			fromScratch := network.newAccumulator(board)
+			if !reflect.DeepEqual(board.nnue.values, fromScratch.values) {
+				t.Fatalf("after %s the accumulator is off", m)
+			}
+		}
+	}
+}
+
+func TestNetworkFile(t *testing.T) {
+	network := randomNetwork(16, 2)
+
+	var file bytes.Buffer
+	if err := network.Write(&file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadNetwork(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(network, loaded) {
+		t.Error("the network changed on the way through the file")
+	}
+
+	if _, err := ReadNetwork(bytes.NewReader(file.Bytes()[:100])); err == nil {
+		t.Error("a cut off file should be an error")
+	}
+	if _, err := ReadNetwork(bytes.NewReader([]byte("NOPE\x01\x00\x00\x00\x10\x00\x00\x00"))); err == nil {
+		t.Error("a file that isn't a network should be an error")
+	}
+}
+
+//the same position with the colors the other way around is worth the same to the side to move
+func TestNetworkSymmetry(t *testing.T) {
+	evaluator := NewNetworkEvaluator(randomNetwork(32, 3))
+
+	pairs := [][2]string{
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1"},
+		{"r1bq1rk1/ppp2ppp/2n2n2/3pp3/1bPP4/2N1PN2/PP3PPP/R1BQKB1R w KQ - 0 6", "r1bqkb1r/pp3ppp/2n1pn2/1Bpp4/3PP3/2N2N2/PPP2PPP/R1BQ1RK1 b kq - 0 6"},
+	}
+
+	for _, pair := range pairs {
+		board, _ := BoardFromFEN(pair[0])
+		mirrored, _ := BoardFromFEN(pair[1])
+		if evaluator.Evaluate(board) != -evaluator.Evaluate(mirrored) {
+			t.Errorf("%s is %v, the mirrored position is %v", pair[0], evaluator.Evaluate(board), evaluator.Evaluate(mirrored))
+		}
+	}
+}
+
+func TestNetworkSearch(t *testing.T) {
+	evaluator := NewNetworkEvaluator(randomNetwork(16, 4))
+	board := NewBoard()
+
+	lines := board.Search(SearchOptions{Depth: 2, Evaluator: evaluator})
+	if len(lines) == 0 {
+		t.Fatal("the search should find a move")
+	}
+	if board.nnue != nil {
+		t.Error("the search shouldn't leave an accumulator on the caller's board")
+	}
+
+	//mates don't depend on the network
+	mate, _ := BoardFromFEN("R1k5/6R1/8/8/8/3K4/8/8 b - - 11 6")
+	if evaluator.Evaluate(mate) != mateScore {
+		t.Error("checkmate should be checkmate, got", evaluator.Evaluate(mate))
+	}
+}
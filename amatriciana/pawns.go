@@ -0,0 +1,243 @@
+package amatriciana
+
+import "sync/atomic"
+
+//pawn structure: doubled, isolated, backward, connected, passed and candidate
+//passed pawns. pawns don't move much, so the part that only depends on them
+//gets remembered in a table keyed by the pawn hash
+
+//what the pawn table remembers about a pawn structure
+type pawnEntry struct {
+	//by color, each from its own side's point of view, in centipawns
+	score [2]taperedScore
+	//the squares of the passed pawns, by color
+	passed [2]uint64
+}
+
+//lock-free like the transposition table: the key is xor'd with all the data
+type pawnSlot struct {
+	check uint64
+	data  [3]uint64
+}
+
+//every evaluator has its own, the same pawns score differently with other weights
+const pawnTableSize = 1 << 14
+
+func (e *Evaluator) probePawnTable(key uint64) (pawnEntry, bool) {
+	slot := &e.pawnTable[key%pawnTableSize]
+	check := atomic.LoadUint64(&slot.check)
+	var data [3]uint64
+	for i := range data {
+		data[i] = atomic.LoadUint64(&slot.data[i])
+	}
+
+	if check^data[0]^data[1]^data[2] != key {
+		return pawnEntry{}, false
+	}
+
+	//the four scores are 16 bits each
+	var score [2]taperedScore
+	for col := range score {
+		score[col].mg = int(int16(data[0] >> uint(32*col)))
+		score[col].eg = int(int16(data[0] >> uint(32*col+16)))
+	}
+	return pawnEntry{score, [2]uint64{data[1], data[2]}}, true
+}
+
+func (e *Evaluator) storePawnTable(key uint64, entry pawnEntry) {
+	slot := &e.pawnTable[key%pawnTableSize]
+	data := [3]uint64{0, entry.passed[0], entry.passed[1]}
+	for col, score := range entry.score {
+		data[0] |= uint64(uint16(int16(score.mg)))<<uint(32*col) | uint64(uint16(int16(score.eg)))<<uint(32*col+16)
+	}
+
+	for i := range data {
+		atomic.StoreUint64(&slot.data[i], data[i])
+	}
+	atomic.StoreUint64(&slot.check, key^data[0]^data[1]^data[2])
+}
+
+//PawnStructure is the pawn part of the evaluation, from white's point of view
+func (b Board) PawnStructure() Score {
+	return Score(whiteMinusBlack(defaultEvaluator.pawnStructure(b)).taper(b.phase))
+}
+
+//the pawn score by color, with the part that depends on the kings
+func (e *Evaluator) pawnStructure(b Board) [2]taperedScore {
+	key := b.pawnHash()
+	entry, found := e.probePawnTable(key)
+	if !found {
+		entry = e.evaluatePawns(b)
+		e.storePawnTable(key, entry)
+	}
+
+	score := entry.score
+	kings := e.passedPawnKings(b, entry.passed)
+	for col := range score {
+		score[col].eg += kings[col]
+	}
+	return score
+}
+
+//where the pawns of both colors are, with a square of padding all around
+//so looking next to the edge files doesn't need checks
+type pawnGrid [2][10][10]bool
+
+func (b Board) pawnGrid() pawnGrid {
+	var grid pawnGrid
+	for _, p := range b.pieces {
+		if p.pieceType == pawn {
+			grid[p.color.index()][p.position.x][p.position.y] = true
+		}
+	}
+	return grid
+}
+
+func (g pawnGrid) has(col, file, rank int) bool {
+	return rank >= 0 && rank <= 9 && g[col][file][rank]
+}
+
+//how many pawns of a color are on file, between the ranks from and to included
+func (g pawnGrid) count(col, file, from, to int) int {
+	if from > to {
+		from, to = to, from
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to > 8 {
+		to = 8
+	}
+
+	count := 0
+	for y := from; y <= to; y++ {
+		if g[col][file][y] {
+			count++
+		}
+	}
+	return count
+}
+
+func (e *Evaluator) evaluatePawns(b Board) pawnEntry {
+	grid := b.pawnGrid()
+
+	var entry pawnEntry
+	for _, p := range b.pieces {
+		if p.pieceType != pawn {
+			continue
+		}
+
+		score, passed := e.pawnScore(grid, p)
+		entry.score[p.color.index()].add(score)
+
+		if passed {
+			entry.passed[p.color.index()] |= 1 << uint(p.position.index())
+		}
+	}
+
+	return entry
+}
+
+//the score of a single pawn from its own side's point of view
+func (e *Evaluator) pawnScore(g pawnGrid, p piece) (taperedScore, bool) {
+	params := &e.params
+	us, them := p.color.index(), (!p.color).index()
+	x, y := p.position.x, p.position.y
+
+	//forward is where the pawn goes, the last rank is where it promotes
+	forward, last := 1, 8
+	rank := y
+	if p.color == black {
+		forward, last = -1, 1
+		rank = 9 - y
+	}
+	ahead, behind := y+forward, y-forward
+
+	var score taperedScore
+
+	if g.count(us, x, ahead, last) > 0 {
+		score.add(params.DoubledPawn.score())
+	}
+
+	phalanx := g.has(us, x-1, y) || g.has(us, x+1, y)
+	supported := g.has(us, x-1, behind) || g.has(us, x+1, behind)
+	isolated := g.count(us, x-1, 1, 8) == 0 && g.count(us, x+1, 1, 8) == 0
+	opposed := g.count(them, x, ahead, last) > 0
+
+	if phalanx || supported {
+		bonus := params.ConnectedPawn[rank-1]
+		if supported {
+			bonus += params.ConnectedPawn[rank-1] / 2
+		}
+		score.add(taperedScore{bonus, bonus})
+	}
+
+	if isolated {
+		score.add(params.IsolatedPawn.score())
+	} else if !phalanx && !supported {
+		//nothing can come up to protect it, and it can't go forward
+		//without getting taken
+		first := y - forward*7
+		helpers := g.count(us, x-1, first, y) + g.count(us, x+1, first, y)
+		stopAttacked := g.has(them, x-1, y+2*forward) || g.has(them, x+1, y+2*forward)
+		if helpers == 0 && stopAttacked {
+			score.add(params.BackwardPawn.score())
+		}
+	}
+
+	sentries := g.count(them, x-1, ahead, last) + g.count(them, x+1, ahead, last)
+	if !opposed && sentries == 0 && g.count(us, x, ahead, last) == 0 {
+		score.add(params.PassedPawn[rank-1].score())
+		return score, true
+	}
+
+	//a pawn with more friends than enemies on the files next to it can
+	//become a passed pawn
+	if !opposed {
+		first := y - forward*7
+		supporters := g.count(us, x-1, first, y) + g.count(us, x+1, first, y)
+		if supporters >= sentries {
+			score.add(params.CandidatePawn[rank-1].score())
+		}
+	}
+
+	return score, false
+}
+
+//in the endgame a passed pawn is worth more the further the enemy king is from
+//the square in front of it, and the closer its own king is
+func (e *Evaluator) passedPawnKings(b Board, passed [2]uint64) [2]int {
+	var kings [2]int
+	for _, p := range b.pieces {
+		if p.pieceType == king {
+			kings[p.color.index()] = p.position.index()
+		}
+	}
+
+	var score [2]int
+	for square := 0; square < 64; square++ {
+		for us, forward := range [...]int{8, -8} {
+			if passed[us]&(1<<uint(square)) == 0 {
+				continue
+			}
+
+			rank := square/8 + 1
+			if us == 1 {
+				rank = 8 - square/8
+			}
+
+			stop := square + forward
+			score[us] += e.params.PassedPawnKings[rank-1] *
+				(5*minInt(squareDistance(kings[1-us], stop), 5) - 2*minInt(squareDistance(kings[us], stop), 5))
+		}
+	}
+
+	return score
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
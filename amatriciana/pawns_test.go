@@ -0,0 +1,72 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestPassedPawns(t *testing.T) {
+	tests := []struct {
+		fen    string
+		square string
+		passed bool
+	}{
+		{"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", "d5", true},
+		{"4k3/4p3/8/3P4/8/8/8/4K3 w - - 0 1", "d5", false},
+		{"4k3/8/8/3p4/8/8/8/4K3 w - - 0 1", "d5", true},
+		//only the front one of doubled pawns is passed
+		{"4k3/8/8/3P4/3P4/8/8/4K3 w - - 0 1", "d4", false},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		square, _ := parsexy(test.square)
+		p, _ := board.pieceAtPosition(square)
+
+		entry := defaultEvaluator.evaluatePawns(board)
+		passed := entry.passed[p.color.index()]&(1<<uint(square.index())) != 0
+		if passed != test.passed {
+			t.Errorf("%s: expected %s passed to be %v", test.fen, test.square, test.passed)
+		}
+	}
+}
+
+//doubled pawns used to be added as a bonus
+func TestDoubledPawnsArePenalized(t *testing.T) {
+	healthy, _ := BoardFromFEN("4k3/pp6/8/8/8/8/PP6/4K3 w - - 0 1")
+	doubled, _ := BoardFromFEN("4k3/pp6/8/8/8/1P6/1P6/4K3 w - - 0 1")
+
+	if score := defaultEvaluator.evaluatePawns(healthy).score; score[0] != score[1] {
+		t.Error("the same pawns on both sides should be even, got", defaultEvaluator.evaluatePawns(healthy).score)
+	}
+	if doubled.PawnStructure() >= healthy.PawnStructure() {
+		t.Errorf("doubled pawns should be worse: %v, %v", doubled.PawnStructure(), healthy.PawnStructure())
+	}
+}
+
+func TestPassedPawnKingDistance(t *testing.T) {
+	far, _ := BoardFromFEN("7k/8/8/3P4/8/8/8/4K3 w - - 0 1")
+	near, _ := BoardFromFEN("8/8/3k4/3P4/8/8/8/4K3 w - - 0 1")
+
+	if far.PawnStructure() <= near.PawnStructure() {
+		t.Errorf("the pawn should be worth more with the king far: %v, %v", far.PawnStructure(), near.PawnStructure())
+	}
+}
+
+func TestPawnTable(t *testing.T) {
+	board, _ := BoardFromFEN("r1bqkbnr/pp1ppppp/2n5/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+
+	first := board.PawnStructure()
+	entry, found := defaultEvaluator.probePawnTable(board.pawnHash())
+	if !found {
+		t.Fatal("the pawn structure should be in the table")
+	}
+	if entry != defaultEvaluator.evaluatePawns(board) {
+		t.Errorf("expected %v in the table, got %v", defaultEvaluator.evaluatePawns(board), entry)
+	}
+	if board.PawnStructure() != first {
+		t.Error("the table should give back the same score")
+	}
+}
@@ -0,0 +1,502 @@
+package amatriciana
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//Game is a game read from a PGN file
+type Game struct {
+	Tags map[string]string
+	//Start is where the game begins, it's only different from
+	//the usual starting position when there's a FEN tag
+	Start  Board
+	Moves  []move
+	Result string
+	//Annotations go with the moves of the same index, there can be fewer
+	//of them than moves. ReadPGN doesn't fill them in
+	Annotations []Annotation
+}
+
+//Annotation is what gets written after a move: numeric annotation glyphs
+//like 2 for ? and 4 for ??, a comment, and variations that get played
+//instead of the move
+type Annotation struct {
+	NAGs       []int
+	Comment    string
+	Variations [][]move
+}
+
+//ReadPGN reads every game in a PGN file. comments, variations and
+//annotations are skipped, only the main line is kept
+func ReadPGN(r io.Reader) ([]Game, error) {
+	games := make([]Game, 0)
+	reader := bufio.NewReader(r)
+
+	game := newGame()
+	board := game.Start.Clone()
+	inMoves := false
+
+	finish := func(result string) {
+		game.Result = result
+		games = append(games, game)
+		game = newGame()
+		board = game.Start.Clone()
+		inMoves = false
+	}
+
+	for {
+		token, err := nextPGNToken(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return games, err
+		}
+
+		switch {
+		case strings.HasPrefix(token, "["):
+			//a tag after some moves means the last game had no result
+			if inMoves {
+				finish("*")
+			}
+
+			name, value := parseTag(token)
+			game.Tags[name] = value
+			if name == "FEN" {
+				start, err := BoardFromFEN(value)
+				if err != nil {
+					return games, fmt.Errorf("game %d: %s", len(games)+1, err.Error())
+				}
+				game.Start = start
+				board = start.Clone()
+			}
+		case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+			finish(token)
+		default:
+			inMoves = true
+			m, err := board.parseSAN(token)
+			if err != nil {
+				return games, fmt.Errorf("game %d: %s", len(games)+1, err.Error())
+			}
+
+			board.move(m)
+			game.Moves = append(game.Moves, m)
+		}
+	}
+
+	if inMoves {
+		finish("*")
+	}
+
+	return games, nil
+}
+
+func newGame() Game {
+	return Game{
+		Tags:  make(map[string]string),
+		Start: NewBoard(),
+		Moves: make([]move, 0),
+	}
+}
+
+//Position is the board after the moves of the game
+func (g Game) Position() Board {
+	b := g.Start.Clone()
+	for _, m := range g.Moves {
+		b.move(m)
+	}
+	return b
+}
+
+//gives back tags, moves and results, everything else gets skipped
+func nextPGNToken(reader *bufio.Reader) (string, error) {
+	for {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			continue
+		case char == '[':
+			tag, err := reader.ReadString(']')
+			return "[" + tag, err
+		case char == '{':
+			_, err := reader.ReadString('}')
+			if err != nil {
+				return "", err
+			}
+		case char == ';' || char == '%':
+			_, err := reader.ReadString('\n')
+			if err != nil {
+				return "", err
+			}
+		case char == '(':
+			err := skipVariation(reader)
+			if err != nil {
+				return "", err
+			}
+		default:
+			reader.UnreadByte()
+			word, err := readPGNWord(reader)
+			if err != nil && word == "" {
+				return "", err
+			}
+
+			if word == "1-0" || word == "0-1" || word == "1/2-1/2" || word == "*" {
+				return word, nil
+			}
+
+			//move numbers can be glued to the move, like 1.e4 or 12...Nf6
+			digits := strings.TrimLeft(word, "0123456789")
+			if strings.HasPrefix(digits, ".") {
+				word = strings.TrimLeft(digits, ".")
+			}
+
+			if word == "" || strings.HasPrefix(word, "$") {
+				continue
+			}
+
+			return word, nil
+		}
+	}
+}
+
+//reads until a space or the start of something that isn't part of a move
+func readPGNWord(reader *bufio.Reader) (string, error) {
+	var word strings.Builder
+
+	for {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return word.String(), err
+		}
+
+		if strings.IndexByte(" \t\r\n{}()[];", char) >= 0 {
+			reader.UnreadByte()
+			return word.String(), nil
+		}
+
+		word.WriteByte(char)
+	}
+}
+
+//variations can have variations inside them, and comments that have brackets in them
+func skipVariation(reader *bufio.Reader) error {
+	depth := 1
+	for depth > 0 {
+		char, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '{':
+			_, err := reader.ReadString('}')
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//[Name "Value"]
+func parseTag(tag string) (string, string) {
+	tag = strings.TrimSuffix(strings.TrimPrefix(tag, "["), "]")
+
+	space := strings.IndexByte(tag, ' ')
+	if space < 0 {
+		return tag, ""
+	}
+
+	value := strings.TrimSpace(tag[space+1:])
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+	value = strings.Replace(value, "\\\"", "\"", -1)
+
+	return tag[:space], value
+}
+
+//parseSAN turns a move in standard algebraic notation (Nf3, exd5, O-O, e8=Q+)
+//into a move, by looking for the only legal move that fits
+func (b Board) parseSAN(san string) (move, error) {
+	original := san
+	san = strings.TrimRight(san, "+#!?")
+
+	legalMoves := b.moves(b.turn)
+
+	if san == "O-O" || san == "0-0" || san == "O-O-O" || san == "0-0-0" {
+		castle := shortCastle
+		if len(san) == 5 {
+			castle = longCastle
+		}
+
+		for _, m := range legalMoves {
+			if m.moveType == castle {
+				return m, nil
+			}
+		}
+
+		return move{}, fmt.Errorf("can't castle in %s", original)
+	}
+
+	//e8=Q, and sometimes e8Q
+	promotesInto := pawn
+	if i := strings.IndexByte(san, '='); i >= 0 && i+1 < len(san) {
+		pt, err := pieceTypeFromFen(san[i+1])
+		if err != nil {
+			return move{}, err
+		}
+		promotesInto = pt
+		san = san[:i]
+	} else if len(san) > 2 && strings.IndexByte("NBRQ", san[len(san)-1]) >= 0 {
+		pt, _ := pieceTypeFromFen(san[len(san)-1])
+		promotesInto = pt
+		san = san[:len(san)-1]
+	}
+
+	movingPiece := pawn
+	if len(san) > 0 && strings.IndexByte("NBRQK", san[0]) >= 0 {
+		movingPiece, _ = pieceTypeFromFen(san[0])
+		san = san[1:]
+	}
+
+	san = strings.Replace(san, "x", "", -1)
+	san = strings.Replace(san, "-", "", -1)
+	if len(san) < 2 {
+		return move{}, fmt.Errorf("can't understand the move %s", original)
+	}
+
+	to, err := parsexy(san[len(san)-2:])
+	if err != nil {
+		return move{}, fmt.Errorf("can't understand the move %s: %s", original, err.Error())
+	}
+
+	//whatever is left tells apart two pieces that can go to the same square
+	disambiguation := san[:len(san)-2]
+
+	found := make([]move, 0, 1)
+	for _, m := range legalMoves {
+		if m.piece != movingPiece || m.to != to {
+			continue
+		}
+		if m.moveType == promotion && m.promotesInto != promotesInto {
+			continue
+		}
+		if m.moveType != promotion && promotesInto != pawn {
+			continue
+		}
+		if !fitsDisambiguation(m.from, disambiguation) {
+			continue
+		}
+
+		found = append(found, m)
+	}
+
+	if len(found) == 0 {
+		return move{}, fmt.Errorf("%s isn't a legal move in %s", original, b.FEN())
+	}
+	if len(found) > 1 {
+		return move{}, fmt.Errorf("%s is ambiguous in %s", original, b.FEN())
+	}
+
+	return found[0], nil
+}
+
+func fitsDisambiguation(from xy, disambiguation string) bool {
+	for _, char := range []byte(disambiguation) {
+		if char >= 'a' && char <= 'h' && int(char-'a')+1 != from.x {
+			return false
+		}
+		if char >= '1' && char <= '8' && int(char-'1')+1 != from.y {
+			return false
+		}
+	}
+
+	return true
+}
+
+//san writes a legal move in standard algebraic notation, with the name of
+//the piece, as much of the square it comes from as it takes to tell it apart,
+//and + or # at the end
+func (b Board) san(m move) string {
+	var out strings.Builder
+
+	switch {
+	case m.moveType == shortCastle:
+		out.WriteString("O-O")
+	case m.moveType == longCastle:
+		out.WriteString("O-O-O")
+	case m.piece == pawn:
+		if b.isCapture(m) {
+			out.WriteByte(m.from.String()[0])
+			out.WriteByte('x')
+		}
+		out.WriteString(m.to.String())
+		if m.moveType == promotion {
+			out.WriteByte('=')
+			out.WriteString(strings.ToUpper(string(m.promotesInto.letter())))
+		}
+	default:
+		out.WriteString(strings.ToUpper(string(m.piece.letter())))
+
+		sameFile, sameRank, ambiguous := false, false, false
+		for _, other := range b.moves(b.turn) {
+			if other.piece != m.piece || other.to != m.to || other.from == m.from {
+				continue
+			}
+			ambiguous = true
+			sameFile = sameFile || other.from.x == m.from.x
+			sameRank = sameRank || other.from.y == m.from.y
+		}
+		from := m.from.String()
+		if ambiguous && (!sameFile || sameRank) {
+			out.WriteByte(from[0])
+		}
+		if ambiguous && sameFile {
+			out.WriteByte(from[1])
+		}
+
+		if b.isCapture(m) {
+			out.WriteByte('x')
+		}
+		out.WriteString(m.to.String())
+	}
+
+	after := b.clonePosition()
+	after.move(m)
+	if after.isKingInCheck(after.turn) {
+		if len(after.moves(after.turn)) == 0 {
+			out.WriteByte('#')
+		} else {
+			out.WriteByte('+')
+		}
+	}
+
+	return out.String()
+}
+
+//the tags every game should have, in the order they should be in
+var sevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+//WritePGN writes the game in PGN, the seven tag roster first and the other
+//tags after it in alphabetical order. a game that doesn't start from the
+//usual position gets the FEN and SetUp tags
+func (g Game) WritePGN(w io.Writer) error {
+	tags := make(map[string]string, len(g.Tags)+2)
+	for name, value := range g.Tags {
+		tags[name] = value
+	}
+	for _, name := range sevenTagRoster {
+		if _, found := tags[name]; !found {
+			tags[name] = "?"
+		}
+	}
+	if g.Result != "" {
+		tags["Result"] = g.Result
+	}
+	if fen := g.Start.FEN(); fen != NewBoard().FEN() {
+		tags["FEN"] = fen
+		tags["SetUp"] = "1"
+	}
+
+	names := make([]string, 0, len(tags))
+	inRoster := make(map[string]bool, len(sevenTagRoster))
+	for _, name := range sevenTagRoster {
+		names = append(names, name)
+		inRoster[name] = true
+	}
+	others := make([]string, 0, len(tags))
+	for name := range tags {
+		if !inRoster[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	names = append(names, others...)
+
+	var out strings.Builder
+	for _, name := range names {
+		value := strings.Replace(tags[name], `"`, `\"`, -1)
+		fmt.Fprintf(&out, "[%s \"%s\"]\n", name, value)
+	}
+	out.WriteByte('\n')
+
+	//the movetext gets wrapped before it goes over 80 characters
+	tokens := movetext(g.Start, g.Moves, g.Annotations)
+	tokens = append(tokens, tags["Result"])
+
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > 80 {
+			out.WriteByte('\n')
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			out.WriteByte(' ')
+			lineLength++
+		}
+		out.WriteString(token)
+		lineLength += len(token)
+	}
+	out.WriteString("\n\n")
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+//the tokens of the moves played from board with their annotations. black's
+//move gets its number again when something comes between it and white's
+func movetext(board Board, moves []move, annotations []Annotation) []string {
+	board = board.clonePosition()
+	tokens := make([]string, 0, len(moves)+1)
+	numbered := false
+
+	for i, m := range moves {
+		token := board.san(m)
+		if board.turn == white {
+			token = fmt.Sprintf("%d. %s", board.moveNumber, token)
+		} else if !numbered {
+			token = fmt.Sprintf("%d... %s", board.moveNumber, token)
+		}
+		tokens = append(tokens, token)
+		numbered = true
+
+		if i < len(annotations) {
+			annotation := annotations[i]
+			for _, nag := range annotation.NAGs {
+				tokens = append(tokens, fmt.Sprintf("$%d", nag))
+			}
+			if comment := strings.Fields(strings.Replace(annotation.Comment, "}", ")", -1)); len(comment) > 0 {
+				comment[0] = "{" + comment[0]
+				comment[len(comment)-1] += "}"
+				tokens = append(tokens, comment...)
+				numbered = false
+			}
+			for _, variation := range annotation.Variations {
+				if len(variation) == 0 {
+					continue
+				}
+				variationTokens := movetext(board, variation, nil)
+				variationTokens[0] = "(" + variationTokens[0]
+				variationTokens[len(variationTokens)-1] += ")"
+				tokens = append(tokens, variationTokens...)
+				numbered = false
+			}
+		}
+
+		board.move(m)
+	}
+
+	return tokens
+}
@@ -0,0 +1,150 @@
+package amatriciana
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPGN = `[Event "Casual game"]
+[White "Somebody"]
+[Black "Somebody else"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 {the usual} Nc6 3. Bb5 a6 (3... Nf6 4. O-O) 4. Ba4 Nf6 5. O-O $1
+Be7 6. Re1 b5 7. Bb3 d6 8. c3 O-O 1-0
+
+[Event "Promotion"]
+[FEN "8/4P3/8/8/8/k7/8/K7 w - - 0 1"]
+
+1. e8=Q Kb3 2. Qe3+ *
+`
+
+func TestReadPGN(t *testing.T) {
+	games, err := ReadPGN(strings.NewReader(testPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatal("expected two games, got", len(games))
+	}
+
+	ruyLopez := games[0]
+	if ruyLopez.Tags["White"] != "Somebody" || ruyLopez.Result != "1-0" {
+		t.Error("wrong tags:", ruyLopez.Tags, ruyLopez.Result)
+	}
+	if len(ruyLopez.Moves) != 16 {
+		t.Error("expected 16 moves, got", len(ruyLopez.Moves))
+	}
+	if ruyLopez.Moves[8].moveType != shortCastle {
+		t.Error("5. O-O should be castling")
+	}
+
+	promotion := games[1]
+	if promotion.Moves[0].promotesInto != queen || promotion.Result != "*" {
+		t.Error("the promotion game went wrong")
+	}
+}
+
+func TestParseSAN(t *testing.T) {
+	board, err := BoardFromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := board.parseSAN("Rb1"); err != nil {
+		t.Error(err)
+	}
+	if m, err := board.parseSAN("Rhf1"); err != nil || m.from != (xy{8, 1}) {
+		t.Error("Rhf1 should be the h1 rook")
+	}
+	if m, err := board.parseSAN("O-O-O"); err != nil || m.moveType != longCastle {
+		t.Error("couldn't castle long")
+	}
+	if _, err := board.parseSAN("Ke3"); err == nil {
+		t.Error("Ke3 isn't legal")
+	}
+
+	board, err = BoardFromFEN("4k3/8/8/8/8/8/4K3/R6R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := board.parseSAN("Rd1"); err == nil {
+		t.Error("Rd1 is ambiguous")
+	}
+	if m, err := board.parseSAN("Rad1+"); err != nil || m.from != (xy{1, 1}) {
+		t.Error("Rad1 should be the a1 rook")
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		fen  string
+		move string
+		san  string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1f3", "Nf3"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "e4d5", "exd5"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1d1", "Rad1"},
+		{"4k3/8/8/8/8/R7/8/R3K3 w - - 0 1", "a3a2", "R3a2"},
+		{"4k3/8/8/8/8/8/2Q1Q3/4K1Q1 w - - 0 1", "e2e3", "Qee3+"},
+		{"8/7k/8/2Q5/8/2Q1Q3/8/7K w - - 0 1", "c3d4", "Qc3d4"},
+		{"8/4P3/8/8/8/k7/8/K7 w - - 0 1", "e7e8q", "e8=Q"},
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := board.parseMove(test.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if san := board.san(m); san != test.san {
+			t.Errorf("%s in %s: expected %s, got %s", test.move, test.fen, test.san, san)
+		}
+	}
+}
+
+func TestWritePGN(t *testing.T) {
+	games, err := ReadPGN(strings.NewReader(testPGN))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	for _, game := range games {
+		if err := game.WritePGN(&out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	written := out.String()
+	if !strings.HasPrefix(written, "[Event \"Casual game\"]\n[Site \"?\"]\n") {
+		t.Errorf("expected the seven tag roster first, got\n%s", written)
+	}
+	if !strings.Contains(written, "5. O-O Be7 6. Re1 b5") || !strings.Contains(written, "[SetUp \"1\"]") {
+		t.Errorf("unexpected pgn\n%s", written)
+	}
+
+	again, err := ReadPGN(strings.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(games) {
+		t.Fatalf("expected %d games, got %d", len(games), len(again))
+	}
+	for i := range games {
+		if again[i].Result != games[i].Result || len(again[i].Moves) != len(games[i].Moves) {
+			t.Errorf("game %d changed when written and read again", i+1)
+		}
+		for j := range games[i].Moves {
+			if again[i].Moves[j] != games[i].Moves[j] {
+				t.Errorf("game %d, move %d: expected %v, got %v", i+1, j+1, games[i].Moves[j], again[i].Moves[j])
+			}
+		}
+	}
+}
@@ -84,7 +84,6 @@ func pieceTypeFromFen(char byte) (pieceType, error) {
 	case 'k':
 		return king, nil
 	default:
-		panic(fmt.Errorf("invalid piece : %c", char))
 		return 0, fmt.Errorf("invalid piece : %c", char)
 
 	}
@@ -227,32 +226,37 @@ func (b Board) pawnMoves(pos xy, col color) []move {
 		otherSide = xy{0, -1}
 	}
 
-	moveType := normalMove
-	if (pos.plus(otherSide).y > 7 && col == white) || (pos.plus(otherSide).y < 2 && col == black) {
-		moveType = promotion
-	}
-
 	//check for captures
 	right, canCaptureRight := b.pieceAtPosition(pos.plus(otherSide).plus(xy{1, 0}))
 	if canCaptureRight && right.color != col {
-		moves = append(moves, move{pawn, col, pos, right.position, moveType, pawn})
+		moves = appendPawnMove(moves, move{pawn, col, pos, right.position, normalMove, pawn})
 	}
 
 	left, canCaptureLeft := b.pieceAtPosition(pos.plus(otherSide).plus(xy{-1, 0}))
 	if canCaptureLeft && left.color != col {
-		moves = append(moves, move{pawn, col, pos, left.position, moveType, pawn})
+		moves = appendPawnMove(moves, move{pawn, col, pos, left.position, normalMove, pawn})
+	}
+
+	//en passant: the square is only set right after the double push,
+	//so if it's one of our capture squares the enemy pawn is right beside us
+	if (b.enPassant != xy{0, 0}) && b.enPassant.y == pos.plus(otherSide).y &&
+		(b.enPassant.x == pos.x+1 || b.enPassant.x == pos.x-1) {
+		victim, isThere := b.pieceAtPosition(xy{b.enPassant.x, pos.y})
+		if isThere && victim.pieceType == pawn && victim.color != col {
+			moves = append(moves, move{pawn, col, pos, b.enPassant, enPassant, pawn})
+		}
 	}
 
 	//check if it can move forwards by one
 	_, cantPush := b.pieceAtPosition(pos.plus(otherSide))
-	if !cantPush {
-		moves = append(moves, move{pawn, col, pos, pos.plus(otherSide), moveType, pawn})
+	if !cantPush && isInBounds(pos.plus(otherSide)) {
+		moves = appendPawnMove(moves, move{pawn, col, pos, pos.plus(otherSide), normalMove, pawn})
 
 		if (pos.y == 2 && col == white) || (pos.y == 7 && col == black) {
 			pushTwoPos := pos.plus(otherSide).plus(otherSide)
 			_, cantPush := b.pieceAtPosition(pushTwoPos)
 			if !cantPush {
-				moves = append(moves, move{pawn, col, pos, pushTwoPos, moveType, pawn})
+				moves = append(moves, move{pawn, col, pos, pushTwoPos, normalMove, pawn})
 			}
 		}
 	}
@@ -260,6 +264,21 @@ func (b Board) pawnMoves(pos xy, col color) []move {
 	return moves
 }
 
+//a pawn reaching the last rank turns into one move for each piece it can become
+func appendPawnMove(moves []move, m move) []move {
+	if m.to.y != 8 && m.to.y != 1 {
+		return append(moves, m)
+	}
+
+	for _, promotesInto := range [...]pieceType{queen, rook, bishop, knight} {
+		m.moveType = promotion
+		m.promotesInto = promotesInto
+		moves = append(moves, m)
+	}
+
+	return moves
+}
+
 func (b Board) pawnCaptures(pos xy, col color) []move {
 	var otherSide xy
 
@@ -315,10 +334,11 @@ func (b Board) kingMoves(pos xy, col color) []move {
 	if canCastle[1] {
 		_, occupied1 := b.pieceAtPosition(kingPos.plus(xy{-1, 0}))
 		_, occupied2 := b.pieceAtPosition(kingPos.plus(xy{-2, 0}))
+		_, occupied3 := b.pieceAtPosition(kingPos.plus(xy{-3, 0}))
 		if !b.isKingInCheck(col) &&
 			!b.isSquareInCheck(kingPos.plus(xy{-1, 0}), col) &&
 			!b.isSquareInCheck(kingPos.plus(xy{-2, 0}), col) &&
-			!occupied1 && occupied2 {
+			!occupied1 && !occupied2 && !occupied3 {
 			moves = append(moves, move{king, col, kingPos, kingPos.plus(xy{-2, 0}), longCastle, pawn})
 		}
 	}
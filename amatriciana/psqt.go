@@ -0,0 +1,198 @@
+package amatriciana
+
+//piece-square tables: how much a piece is worth on a square on top of its
+//material, once for the middlegame and once for the endgame. the board keeps
+//the sum up to date in move(), the evaluation blends the two by how much
+//material is left
+
+//taperedScore is a score in centipawns that changes over the game
+type taperedScore struct {
+	mg, eg int
+}
+
+func (s *taperedScore) add(other taperedScore) {
+	s.mg += other.mg
+	s.eg += other.eg
+}
+
+func (s *taperedScore) sub(other taperedScore) {
+	s.mg -= other.mg
+	s.eg -= other.eg
+}
+
+//the score from white's point of view out of the two sides' scores
+func whiteMinusBlack(scores [2]taperedScore) taperedScore {
+	score := scores[0]
+	score.sub(scores[1])
+	return score
+}
+
+//blends the middlegame and the endgame score, phase goes from 0
+//(only kings and pawns) to maxPhase (everything still on the board)
+func (s taperedScore) taper(phase int) int {
+	if phase > maxPhase {
+		phase = maxPhase
+	}
+	return (s.mg*phase + s.eg*(maxPhase-phase)) / maxPhase
+}
+
+//how much every piece counts towards the game phase
+var phaseWeight = [...]int{pawn: 0, knight: 1, bishop: 1, rook: 2, queen: 4, king: 0}
+
+const maxPhase = 24
+
+//the tables are seen from white's side, with the eighth rank at the top
+var psqtMg = [6][64]int{
+	pawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	knight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	bishop: {
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	rook: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	},
+	queen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	//hide behind the pawns
+	king: {
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	},
+}
+
+var psqtEg = [6][64]int{
+	//the closer to promoting the better, wherever the pawn is
+	pawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		80, 80, 80, 80, 80, 80, 80, 80,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		30, 30, 30, 30, 30, 30, 30, 30,
+		15, 15, 15, 15, 15, 15, 15, 15,
+		5, 5, 5, 5, 5, 5, 5, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	knight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	bishop: {
+		-15, -10, -10, -10, -10, -10, -10, -15,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-15, -10, -10, -10, -10, -10, -10, -15,
+	},
+	rook: {
+		5, 5, 5, 5, 5, 5, 5, 5,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	queen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 5, 10, 10, 10, 10, 5, -10,
+		-5, 5, 10, 15, 15, 10, 5, -5,
+		-5, 5, 10, 15, 15, 10, 5, -5,
+		-10, 5, 10, 10, 10, 10, 5, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	//come out and help the pawns
+	king: {
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	},
+}
+
+//what the piece adds to its side's score
+func pieceSquare(p piece) taperedScore {
+	row := 8 - p.position.y
+	if p.color == black {
+		row = p.position.y - 1
+	}
+	square := row*8 + p.position.x - 1
+
+	return taperedScore{psqtMg[p.pieceType][square], psqtEg[p.pieceType][square]}
+}
+
+//the board's piece-square score and phase, from scratch
+func (b *Board) initPSQ() {
+	b.psq = [2]taperedScore{}
+	b.phase = 0
+	for _, p := range b.pieces {
+		b.psq[p.color.index()].add(pieceSquare(p))
+		b.phase += phaseWeight[p.pieceType]
+	}
+}
+
+//PieceSquares is the piece-square part of the evaluation from white's point of view
+func (b Board) PieceSquares() Score {
+	return Score(whiteMinusBlack(b.psq).taper(b.phase))
+}
@@ -0,0 +1,63 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+//the score move() keeps has to be the same as the one from scratch
+func TestIncrementalPieceSquares(t *testing.T) {
+	games := []struct {
+		fen   string
+		moves []string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []string{
+			"e2e4", "d7d5", "e4d5", "c7c5", "d5c6", "b7c6", "g1f3", "g8f6",
+			"f1e2", "e7e6", "e1g1", "f8e7", "d2d4", "e8g8", "c1g5", "d8a5",
+		}},
+		{"r3k3/1P6/8/8/8/8/6p1/R3K2N w Qq - 0 1", []string{"b7a8q", "e8e7", "e1c1", "g2h1n"}},
+	}
+
+	for _, game := range games {
+		board, err := BoardFromFEN(game.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, m := range game.moves {
+			err := board.PerformMove(m)
+			if err != nil {
+				t.Fatal(m, err)
+			}
+
+			fromScratch := board.Clone()
+			fromScratch.initPSQ()
+			if board.psq != fromScratch.psq || board.phase != fromScratch.phase {
+				t.Fatalf("after %s expected %v phase %d, got %v phase %d",
+					m, fromScratch.psq, fromScratch.phase, board.psq, board.phase)
+			}
+		}
+	}
+}
+
+func TestTaperedKing(t *testing.T) {
+	start := NewBoard()
+	if start.PieceSquares() != 0 {
+		t.Error("the starting position should be even, got", start.PieceSquares())
+	}
+	if start.phase != maxPhase {
+		t.Error("the starting position should be all middlegame, got phase", start.phase)
+	}
+
+	//the king wants to hide with the queens on and come out without them
+	hiding, _ := BoardFromFEN("rnbqkbnr/8/8/8/8/8/8/RNBQKBNR w - - 0 1")
+	central, _ := BoardFromFEN("rnbqkbnr/8/8/8/4K3/8/8/RNBQ1BNR w - - 0 1")
+	if central.PieceSquares() >= hiding.PieceSquares() {
+		t.Error("the king should stay home in the middlegame")
+	}
+
+	hiding, _ = BoardFromFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	central, _ = BoardFromFEN("4k3/8/8/8/4K3/8/4P3/8 w - - 0 1")
+	if central.PieceSquares() <= hiding.PieceSquares() {
+		t.Error("the king should come out in the endgame")
+	}
+}
@@ -0,0 +1,92 @@
+package amatriciana
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Score is what the evaluation and the search think of a position, in centipawns.
+//the scores within maxSearchDepth of mateScore are forced mates, one less for
+//every ply it takes, and the same thing negated is getting mated
+type Score int
+
+//checkmate on the board
+const mateScore Score = 32000
+
+//IsMate is true if the score is a forced mate for either side
+func (s Score) IsMate() bool {
+	return s > mateScore-maxSearchDepth || s < -mateScore+maxSearchDepth
+}
+
+//MateIn tells you how many moves away the mate in a score is.
+//it's negative if you're the one getting mated, 0 if it isn't a mate
+func (s Score) MateIn() int {
+	if !s.IsMate() {
+		return 0
+	}
+
+	if s > 0 {
+		return (int(mateScore-s) + 1) / 2
+	}
+	return -(int(mateScore+s) + 1) / 2
+}
+
+//Pawns is the score in pawns, for showing it to people
+func (s Score) Pawns() float64 {
+	return float64(s) / 100
+}
+
+//String is like +1.25, or #3 and #-2 for mates
+func (s Score) String() string {
+	if mate := s.MateIn(); mate != 0 {
+		return "#" + strconv.Itoa(mate)
+	}
+	return fmt.Sprintf("%+.2f", s.Pawns())
+}
+
+//UCI is the score like the protocol wants it, cp 125 or mate -2
+func (s Score) UCI() string {
+	if mate := s.MateIn(); mate != 0 {
+		return "mate " + strconv.Itoa(mate)
+	}
+	return "cp " + strconv.Itoa(int(s))
+}
+
+//ParseScore reads a score written like UCI does it
+func ParseScore(s string) (Score, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("%q isn't a score like cp 125 or mate -2", s)
+	}
+
+	n, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a score like cp 125 or mate -2", s)
+	}
+
+	switch fields[0] {
+	case "cp":
+		return Score(n), nil
+	case "mate":
+		if n > 0 {
+			return mateScore - Score(2*n-1), nil
+		}
+		return -mateScore - Score(2*n), nil
+	}
+	return 0, fmt.Errorf("%q isn't a score like cp 125 or mate -2", s)
+}
+
+func maxScore(a, b Score) Score {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minScore(a, b Score) Score {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,521 @@
+package amatriciana
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//the deepest a search can go when nobody tells it when to stop
+const maxSearchDepth = 64
+
+//how big the transposition table is when the caller doesn't bring one
+const defaultHashSize = 16
+
+//SearchOptions tells the search how long to think and what to report
+type SearchOptions struct {
+	//Depth is the last iteration the search will complete. 0 means no limit
+	Depth int
+	//MultiPV is how many root moves get a score and a line of their own
+	MultiPV int
+	//MoveTime is how long the search can take. 0 means no limit
+	MoveTime time.Duration
+	//Nodes is about how many positions the search can look at, all threads
+	//together. it's checked as it goes, so it can go a little over. 0 means no limit
+	Nodes int
+	//Threads is how many goroutines search at the same time. with more than one
+	//the helpers share the transposition table with the main one and the move
+	//gets picked by a vote, so results can change from run to run
+	Threads int
+	//Table is kept between searches if you pass one, otherwise every
+	//search gets a new empty one
+	Table *TranspositionTable
+	//Evaluator scores the positions, nil means the default weights. a table
+	//filled with one evaluator has scores from it, clear it before changing
+	Evaluator *Evaluator
+	//closing Stop makes the search return what it has found so far
+	Stop <-chan struct{}
+	//if PonderHit isn't nil the search is pondering: it ignores MoveTime and
+	//goes on until PonderHit gets closed, then it gets MoveTime from that moment.
+	//the transposition table and everything else carry on like nothing happened
+	PonderHit <-chan struct{}
+	//Info gets called every time an iteration finishes
+	Info func(SearchInfo)
+}
+
+//SearchLine is a root move together with its score and the line the search expects.
+//the score is from the point of view of the side to move
+type SearchLine struct {
+	Move  move
+	Score Score
+	PV    []move
+}
+
+//SearchInfo describes the state of the search after an iteration
+type SearchInfo struct {
+	Depth int
+	Nodes int
+	Time  time.Duration
+	Lines []SearchLine
+}
+
+type searcher struct {
+	stop     <-chan struct{}
+	deadline time.Time
+	//while pondering there's no deadline, it gets set on the ponderhit
+	ponderHit <-chan struct{}
+	moveTime  time.Duration
+	maxNodes  int64
+	table     *TranspositionTable
+	evaluator *Evaluator
+	//every thread adds its nodes to the same counter
+	nodes   *int64
+	stopped bool
+	//the first iteration always finishes, so there's always a move to play
+	canStop bool
+}
+
+//what a thread had found when it stopped
+type threadResult struct {
+	depth int
+	lines []SearchLine
+}
+
+//Search looks for the best moves in the position, deepening one ply at a time.
+//the lines come back best first: with MultiPV set to k every line after the
+//first is the best move once the moves of the lines before it are taken away
+func (b Board) Search(opts SearchOptions) []SearchLine {
+	start := time.Now()
+
+	if opts.Depth <= 0 {
+		opts.Depth = maxSearchDepth
+	}
+	if opts.MultiPV < 1 {
+		opts.MultiPV = 1
+	}
+	if opts.Threads < 1 {
+		opts.Threads = 1
+	}
+	if opts.Table == nil {
+		opts.Table = NewTranspositionTable(defaultHashSize)
+	}
+	if opts.Evaluator == nil {
+		opts.Evaluator = defaultEvaluator
+	}
+	//the children get the accumulator with the rest of the board
+	if opts.Evaluator.network != nil {
+		b.nnue = opts.Evaluator.network.newAccumulator(b)
+	}
+
+	var deadline time.Time
+	if opts.MoveTime > 0 && opts.PonderHit == nil {
+		deadline = start.Add(opts.MoveTime)
+	}
+
+	//with a tablebase for the position only the moves that keep the best
+	//result get searched, the search alone can take forever to find the way
+	rootMoves := b.orderMoves(b.tablebaseRootMoves(b.moves(b.turn)))
+	if len(rootMoves) == 0 {
+		return nil
+	}
+	if opts.MultiPV > len(rootMoves) {
+		opts.MultiPV = len(rootMoves)
+	}
+
+	var nodes int64
+	results := make([]threadResult, opts.Threads)
+
+	//lazy smp: the helpers run the same search as the main thread and only
+	//talk to it through the transposition table. each one skips some depths
+	//with its own pattern, so at any moment they're spread over a few depths
+	//and fill the table with stuff the main thread will need next
+	halt := make(chan struct{})
+	var helpers sync.WaitGroup
+	for id := 1; id < opts.Threads; id++ {
+		helpers.Add(1)
+		go func(id int) {
+			defer helpers.Done()
+
+			helper := searcher{
+				stop:      halt,
+				deadline:  deadline,
+				ponderHit: opts.PonderHit,
+				moveTime:  opts.MoveTime,
+				maxNodes:  int64(opts.Nodes),
+				table:     opts.Table,
+				evaluator: opts.Evaluator,
+				nodes:     &nodes,
+				canStop:   true,
+			}
+			results[id] = helper.iterate(b, rootMoves, id, opts, start, nil)
+		}(id)
+	}
+
+	main := searcher{
+		stop:      opts.Stop,
+		deadline:  deadline,
+		ponderHit: opts.PonderHit,
+		moveTime:  opts.MoveTime,
+		maxNodes:  int64(opts.Nodes),
+		table:     opts.Table,
+		evaluator: opts.Evaluator,
+		nodes:     &nodes,
+	}
+	results[0] = main.iterate(b, rootMoves, 0, opts, start, opts.Info)
+
+	close(halt)
+	helpers.Wait()
+
+	return voteResult(results, opts.MultiPV)
+}
+
+//which depths the helpers skip, the same pattern stockfish used for its lazy
+//smp: helper i skips the depths where (depth+skipPhase[i])/skipSize[i] is odd.
+//past the end of the table the pattern starts over
+var (
+	skipSize  = [...]int{1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4}
+	skipPhase = [...]int{0, 1, 0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+)
+
+//the main thread is thread 0 and never skips anything
+func skipDepth(thread, depth int) bool {
+	if thread == 0 {
+		return false
+	}
+	i := (thread - 1) % len(skipSize)
+	return (depth+skipPhase[i])/skipSize[i]%2 == 1
+}
+
+//iterative deepening up to opts.Depth, without the depths the thread skips
+func (s *searcher) iterate(b Board, rootMoves []move, thread int, opts SearchOptions, start time.Time, info func(SearchInfo)) threadResult {
+	result := threadResult{lines: make([]SearchLine, 0, opts.MultiPV)}
+
+	for depth := 1; depth <= opts.Depth; depth++ {
+		if skipDepth(thread, depth) {
+			continue
+		}
+
+		iteration := make([]SearchLine, 0, opts.MultiPV)
+		excluded := make(map[move]bool, opts.MultiPV)
+
+		for i := 0; i < opts.MultiPV; i++ {
+			line, found := s.searchRoot(b, rootMoves, excluded, depth)
+			if s.stopped || !found {
+				break
+			}
+
+			iteration = append(iteration, line)
+			excluded[line.Move] = true
+		}
+
+		//half finished iterations are thrown away, but the lines that did
+		//finish are still better informed than the last iteration's ones
+		if s.stopped {
+			result.lines = mergeLines(iteration, result.lines)
+			break
+		}
+
+		result = threadResult{depth, iteration}
+		rootMoves = reorderRoot(rootMoves, iteration)
+		s.canStop = true
+
+		if info != nil {
+			info(SearchInfo{depth, int(atomic.LoadInt64(s.nodes)), time.Since(start), iteration})
+		}
+
+		//no point in looking deeper if the position is already decided
+		if len(rootMoves) == 1 || iteration[0].Score.IsMate() {
+			break
+		}
+	}
+
+	return result
+}
+
+//with one thread, or more than one line, the main thread's result is used as
+//it is. otherwise every thread votes for its move, and the deeper it got and
+//the better it thinks the move is the more its vote counts
+func voteResult(results []threadResult, multiPV int) []SearchLine {
+	if len(results) == 1 || multiPV > 1 {
+		return results[0].lines
+	}
+
+	worst := mateScore
+	for _, result := range results {
+		if len(result.lines) > 0 {
+			worst = minScore(worst, result.lines[0].Score)
+		}
+	}
+
+	votes := make(map[move]int)
+	for _, result := range results {
+		if len(result.lines) > 0 {
+			votes[result.lines[0].Move] += int(result.lines[0].Score-worst+100) * result.depth
+		}
+	}
+
+	best := 0
+	for i, result := range results {
+		if len(result.lines) == 0 {
+			continue
+		}
+		if len(results[best].lines) == 0 {
+			best = i
+			continue
+		}
+
+		bestMove := results[best].lines[0].Move
+		move := result.lines[0].Move
+		if votes[move] > votes[bestMove] || (move == bestMove && result.depth > results[best].depth) {
+			best = i
+		}
+	}
+
+	return results[best].lines
+}
+
+//searches every root move that isn't excluded and gives back the best one
+func (s *searcher) searchRoot(b Board, rootMoves []move, excluded map[move]bool, depth int) (SearchLine, bool) {
+	best := SearchLine{Score: -mateScore - 1}
+	found := false
+	alpha, beta := -mateScore-1, mateScore+1
+
+	for _, m := range rootMoves {
+		if excluded[m] {
+			continue
+		}
+
+		child := b.Clone()
+		child.move(m)
+
+		score, pv := s.negamax(child, depth-1, 1, -beta, -alpha)
+		score = -score
+		if s.stopped {
+			break
+		}
+
+		if !found || score > best.Score {
+			best = SearchLine{m, score, append([]move{m}, pv...)}
+			found = true
+		}
+		alpha = maxScore(alpha, score)
+	}
+
+	return best, found && !s.stopped
+}
+
+//alpha-beta from the point of view of the side to move.
+//returns the score and the moves that lead to it
+func (s *searcher) negamax(b Board, depth, ply int, alpha, beta Score) (Score, []move) {
+	atomic.AddInt64(s.nodes, 1)
+	if s.shouldStop() {
+		return 0, nil
+	}
+
+	if depth <= 0 {
+		return s.relativeEvaluation(b, ply), nil
+	}
+
+	hash := b.Hash()
+	entry, found := s.table.probe(hash)
+	if found && entry.depth >= depth {
+		score := scoreFromTT(entry.score, ply)
+		if entry.bound == exactBound ||
+			(entry.bound == lowerBound && score >= beta) ||
+			(entry.bound == upperBound && score <= alpha) {
+			return score, nil
+		}
+	}
+
+	//right after a capture or a pawn move the tablebases know the result.
+	//later on they could be wrong about the fifty move rule
+	if b.halfMoves == 0 {
+		wdl, err := b.ProbeWDL()
+		if err == nil {
+			return tablebaseScore(wdl, ply), nil
+		}
+	}
+
+	moves := b.moves(b.turn)
+	if len(moves) == 0 {
+		if b.isKingInCheck(b.turn) {
+			return -mateScore + Score(ply), nil
+		}
+		return 0, nil
+	}
+
+	moves = b.orderMoves(moves)
+	if found {
+		moves = hashMoveFirst(moves, entry.move)
+	}
+
+	originalAlpha := alpha
+	var bestMove move
+	var bestPV []move
+	for _, m := range moves {
+		child := b.Clone()
+		child.move(m)
+
+		score, pv := s.negamax(child, depth-1, ply+1, -beta, -alpha)
+		score = -score
+		if s.stopped {
+			return 0, nil
+		}
+
+		if score > alpha {
+			alpha = score
+			bestMove = m
+			bestPV = append([]move{m}, pv...)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+
+	bound := exactBound
+	if alpha <= originalAlpha {
+		bound = upperBound
+	} else if alpha >= beta {
+		bound = lowerBound
+	}
+
+	var packed uint16
+	if bestPV != nil {
+		packed = bestMove.pack()
+	}
+	s.table.store(hash, ttEntry{scoreToTT(alpha, ply), depth, bound, packed})
+
+	return alpha, bestPV
+}
+
+//the move the table remembers goes first, it's probably still the best one
+func hashMoveFirst(moves []move, packed uint16) []move {
+	for i, m := range moves {
+		if m.matches(packed) {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = m
+			break
+		}
+	}
+
+	return moves
+}
+
+func (s *searcher) shouldStop() bool {
+	if s.stopped {
+		return true
+	}
+
+	select {
+	case <-s.ponderHit:
+		//the opponent played what we expected, from now on the clock is ticking
+		s.ponderHit = nil
+		if s.moveTime > 0 {
+			s.deadline = time.Now().Add(s.moveTime)
+		}
+	default:
+	}
+
+	if !s.canStop {
+		return false
+	}
+
+	select {
+	case <-s.stop:
+		s.stopped = true
+	default:
+	}
+
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.stopped = true
+	}
+
+	if s.maxNodes > 0 && atomic.LoadInt64(s.nodes) >= s.maxNodes {
+		s.stopped = true
+	}
+
+	return s.stopped
+}
+
+//Evaluate is always from white's point of view, the search wants
+//the side to move's. mates get closer to zero the further away they are
+func (s *searcher) relativeEvaluation(b Board, ply int) Score {
+	eval := s.evaluator.Evaluate(b)
+	if eval >= mateScore {
+		eval -= Score(ply)
+	} else if eval <= -mateScore {
+		eval += Score(ply)
+	}
+
+	if b.turn == black {
+		return -eval
+	}
+	return eval
+}
+
+//captures first, biggest victim and smallest attacker first.
+//the sort is stable so the other moves keep the order they came in
+func (b Board) orderMoves(moves []move) []move {
+	ordered := make([]move, len(moves))
+	copy(ordered, moves)
+
+	priority := make(map[move]int, len(moves))
+	for _, m := range ordered {
+		victim, isCapture := b.pieceAtPosition(m.to)
+		if isCapture {
+			priority[m] = 10*pieceOrder[victim.pieceType] - pieceOrder[m.piece] + 10
+		}
+		if m.moveType == promotion {
+			priority[m] += pieceOrder[m.promotesInto]
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priority[ordered[i]] > priority[ordered[j]]
+	})
+
+	return ordered
+}
+
+//how much a piece is worth when picking which capture to try first
+var pieceOrder = [...]int{pawn: 1, knight: 3, bishop: 3, rook: 5, queen: 9, king: 10}
+
+//moves the lines the search found to the front, in the same order
+func reorderRoot(rootMoves []move, lines []SearchLine) []move {
+	ordered := make([]move, 0, len(rootMoves))
+	inLines := make(map[move]bool, len(lines))
+	for _, line := range lines {
+		ordered = append(ordered, line.Move)
+		inLines[line.Move] = true
+	}
+
+	for _, m := range rootMoves {
+		if !inLines[m] {
+			ordered = append(ordered, m)
+		}
+	}
+
+	return ordered
+}
+
+//fills the lines of an unfinished iteration with the old ones it didn't get to
+func mergeLines(newLines, oldLines []SearchLine) []SearchLine {
+	merged := make([]SearchLine, 0, len(oldLines))
+	merged = append(merged, newLines...)
+
+	for _, old := range oldLines {
+		alreadyThere := false
+		for _, line := range newLines {
+			if line.Move == old.Move {
+				alreadyThere = true
+			}
+		}
+
+		if !alreadyThere && len(merged) < len(oldLines) {
+			merged = append(merged, old)
+		}
+	}
+
+	return merged
+}
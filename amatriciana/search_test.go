@@ -0,0 +1,162 @@
+package amatriciana
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMultiPV(t *testing.T) {
+	board, err := BoardFromFEN("7k/8/6K1/8/8/8/8/R7 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := board.Search(SearchOptions{Depth: 2, MultiPV: 3})
+	if len(lines) != 3 {
+		t.Fatal("expected three lines, got", len(lines))
+	}
+
+	if lines[0].Move.UCIString() != "a1a8" || lines[0].Score.MateIn() != 1 {
+		t.Error("the first line should be the mate, not", lines[0].Move.UCIString())
+	}
+
+	seen := make(map[move]bool)
+	for i, line := range lines {
+		if seen[line.Move] {
+			t.Error("the same root move came up twice")
+		}
+		seen[line.Move] = true
+
+		if i > 0 && line.Score > lines[i-1].Score {
+			t.Error("lines aren't sorted")
+		}
+		if len(line.PV) == 0 || line.PV[0] != line.Move {
+			t.Error("the pv should start with the root move")
+		}
+	}
+}
+
+func TestSearchFindsMateInTwo(t *testing.T) {
+	board, err := BoardFromFEN("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := board.Search(SearchOptions{Depth: 3})
+	if len(lines) == 0 || lines[0].Score.MateIn() != 2 {
+		t.Error("expected a mate in two")
+	}
+}
+
+func TestSingleThreadIsReproducible(t *testing.T) {
+	board, err := BoardFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := board.Search(SearchOptions{Depth: 2, MultiPV: 2})
+	second := board.Search(SearchOptions{Depth: 2, MultiPV: 2})
+
+	if len(first) != len(second) {
+		t.Fatal("different number of lines")
+	}
+	for i := range first {
+		if first[i].Move != second[i].Move || first[i].Score != second[i].Score {
+			t.Error("line", i, "changed between runs")
+		}
+	}
+}
+
+func TestLazySMP(t *testing.T) {
+	board, err := BoardFromFEN("k7/8/2K5/8/8/8/8/7R w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := board.Search(SearchOptions{Depth: 3, Threads: 4})
+	if len(lines) == 0 || lines[0].Score.MateIn() != 2 {
+		t.Error("the helpers got in the way of the mate in two")
+	}
+}
+
+func TestHelpersSkipDepths(t *testing.T) {
+	board, err := BoardFromFEN("4k3/8/8/3p4/8/2N5/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := SearchOptions{Depth: 5, MultiPV: 1}
+
+	//the depths every thread finishes, the main one first
+	seen := make(map[string]bool)
+	for thread := 0; thread < 5; thread++ {
+		var nodes int64
+		s := searcher{table: NewTranspositionTable(1), evaluator: defaultEvaluator, nodes: &nodes}
+
+		var depths []int
+		s.iterate(board, board.orderMoves(board.moves(board.turn)), thread, opts, time.Now(), func(info SearchInfo) {
+			depths = append(depths, info.Depth)
+		})
+
+		if thread == 0 && fmt.Sprint(depths) != "[1 2 3 4 5]" {
+			t.Error("the main thread shouldn't skip anything:", depths)
+		}
+		if len(depths) == 0 || seen[fmt.Sprint(depths)] {
+			t.Errorf("thread %d goes through the same depths as another one: %v", thread, depths)
+		}
+		seen[fmt.Sprint(depths)] = true
+	}
+}
+
+func TestNodeLimit(t *testing.T) {
+	board := NewBoard()
+
+	nodes := 0
+	lines := board.Search(SearchOptions{Nodes: 2000, Info: func(info SearchInfo) {
+		nodes = info.Nodes
+	}})
+	if len(lines) == 0 {
+		t.Fatal("no move came back")
+	}
+	if nodes == 0 || nodes > 2000 {
+		t.Errorf("expected the finished iterations to stay within 2000 nodes, they took %d", nodes)
+	}
+}
+
+func TestTranspositionEntry(t *testing.T) {
+	table := NewTranspositionTable(1)
+	m := move{knight, white, xy{7, 1}, xy{6, 3}, normalMove, pawn}
+	entry := ttEntry{-125, 7, lowerBound, m.pack()}
+
+	table.store(42, entry)
+	stored, found := table.probe(42)
+	if !found || stored != entry || !m.matches(stored.move) {
+		t.Error("got back something else:", stored)
+	}
+
+	if _, found := table.probe(43); found {
+		t.Error("found an entry that was never stored")
+	}
+}
+
+func TestPonderWaitsForPonderHit(t *testing.T) {
+	board := NewBoard()
+	ponderHit := make(chan struct{})
+	stop := make(chan struct{})
+
+	//a pondering search with a tiny move time would stop right away
+	//if it didn't wait for the ponderhit
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		close(ponderHit)
+	}()
+
+	start := time.Now()
+	lines := board.Search(SearchOptions{MoveTime: time.Millisecond, PonderHit: ponderHit, Stop: stop})
+	if time.Since(start) < 300*time.Millisecond {
+		t.Error("the search didn't wait for the ponderhit")
+	}
+	if len(lines) == 0 {
+		t.Error("no move after the ponderhit")
+	}
+}
@@ -0,0 +1,1258 @@
+package amatriciana
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//WDL is a tablebase result from the point of view of the side to move.
+//cursed wins and blessed losses are wins and losses that the fifty move rule
+//turns into draws
+type WDL int
+
+const (
+	WDLLoss        WDL = -2
+	WDLBlessedLoss WDL = -1
+	WDLDraw        WDL = 0
+	WDLCursedWin   WDL = 1
+	WDLWin         WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case WDLLoss:
+		return "loss"
+	case WDLBlessedLoss:
+		return "blessed loss"
+	case WDLDraw:
+		return "draw"
+	case WDLCursedWin:
+		return "cursed win"
+	case WDLWin:
+		return "win"
+	default:
+		return "???"
+	}
+}
+
+//the biggest tables anyone has are seven pieces
+const tbMaxPieces = 7
+
+var (
+	wdlMagic = [4]byte{0x71, 0xe8, 0x23, 0x5d}
+	dtzMagic = [4]byte{0xd7, 0x66, 0x0c, 0xa5}
+)
+
+//flags of every block of pairs
+const (
+	tbFlagSTM         = 1
+	tbFlagMapped      = 2
+	tbFlagWinPlies    = 4
+	tbFlagLossPlies   = 8
+	tbFlagWide        = 16
+	tbFlagSingleValue = 128
+)
+
+//what a probe found out besides the value
+type tbState int
+
+const (
+	tbOK tbState = iota
+	//the best move is a capture or a pawn move, so the table value can't be trusted
+	tbZeroingBestMove
+	//dtz tables only store one side to move, and it's not this one
+	tbChangeSTM
+)
+
+var errNoTable = errors.New("there's no tablebase for this position")
+
+//the tables that SetSyzygyPath found, by name (like KRvK)
+var tablebases = struct {
+	sync.RWMutex
+	wdl       map[string]*tbTable
+	dtz       map[string]*tbTable
+	maxPieces int
+}{}
+
+//SetSyzygyPath looks for syzygy tablebases in the directories in path,
+//separated like in $PATH. the files get read the first time they're needed.
+//an empty path turns tablebases off
+func SetSyzygyPath(path string) error {
+	wdl := make(map[string]*tbTable)
+	dtz := make(map[string]*tbTable)
+	maxPieces := 0
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" || dir == "<empty>" {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			ext := filepath.Ext(file.Name())
+			name := strings.TrimSuffix(file.Name(), ext)
+			if ext != ".rtbw" && ext != ".rtbz" {
+				continue
+			}
+
+			table, err := newTBTable(name, filepath.Join(dir, file.Name()), ext == ".rtbz")
+			if err != nil {
+				//not a table name we understand, somebody else's file
+				continue
+			}
+
+			if ext == ".rtbw" {
+				wdl[name] = table
+				if table.pieceCount > maxPieces {
+					maxPieces = table.pieceCount
+				}
+			} else {
+				dtz[name] = table
+			}
+		}
+	}
+
+	tablebases.Lock()
+	defer tablebases.Unlock()
+
+	tablebases.wdl, tablebases.dtz, tablebases.maxPieces = wdl, dtz, maxPieces
+	return nil
+}
+
+//SyzygyMaxPieces is how many pieces the biggest table found has, kings included.
+//0 means there are no tables
+func SyzygyMaxPieces() int {
+	tablebases.RLock()
+	defer tablebases.RUnlock()
+
+	return tablebases.maxPieces
+}
+
+//ProbeWDL tells you if the side to move wins, draws or loses with perfect play.
+//it needs a table for the position and for everything it can turn into with captures
+func (b Board) ProbeWDL() (WDL, error) {
+	err := b.canProbe()
+	if err != nil {
+		return WDLDraw, err
+	}
+
+	wdl, _, err := b.searchWDL(false)
+	return wdl, err
+}
+
+//ProbeDTZ tells you how many plies it takes, with perfect play, until the next
+//capture or pawn move that keeps the result. it's positive if the side to move
+//wins, negative if it loses and 0 for draws. results off by 100 are cursed
+//wins and blessed losses
+func (b Board) ProbeDTZ() (int, error) {
+	err := b.canProbe()
+	if err != nil {
+		return 0, err
+	}
+
+	return b.probeDTZ()
+}
+
+func (b Board) canProbe() error {
+	if b.whiteCanCastle[0] || b.whiteCanCastle[1] || b.blackCanCastle[0] || b.blackCanCastle[1] {
+		return errors.New("tablebases don't know about castling")
+	}
+
+	maxPieces := SyzygyMaxPieces()
+	if maxPieces == 0 {
+		return errNoTable
+	}
+	if len(b.pieces) > maxPieces {
+		return fmt.Errorf("the tablebases only go up to %d pieces", maxPieces)
+	}
+
+	return nil
+}
+
+//the same thing the generator did: looks at captures (and pawn moves) first,
+//since the tables store whatever compresses best for positions where they win
+func (b Board) searchWDL(checkZeroing bool) (WDL, tbState, error) {
+	bestValue := WDLLoss
+	moves := b.moves(b.turn)
+	moveCount := 0
+
+	for _, m := range moves {
+		if !b.isCapture(m) && (!checkZeroing || m.piece != pawn) {
+			continue
+		}
+		moveCount++
+
+		child := b.Clone()
+		child.move(m)
+
+		value, _, err := child.searchWDL(false)
+		if err != nil {
+			return WDLDraw, tbOK, err
+		}
+		value = -value
+
+		if value > bestValue {
+			bestValue = value
+			if value >= WDLWin {
+				return value, tbZeroingBestMove, nil
+			}
+		}
+	}
+
+	//if every move got searched the table doesn't add anything, and it could be
+	//wrong: tables don't know about en passant
+	noMoreMoves := moveCount > 0 && moveCount == len(moves)
+
+	var value WDL
+	if noMoreMoves {
+		value = bestValue
+	} else {
+		raw, _, err := b.probeTable(false, WDLDraw)
+		if err != nil {
+			return WDLDraw, tbOK, err
+		}
+		value = WDL(raw)
+	}
+
+	if bestValue >= value {
+		if bestValue > WDLDraw || noMoreMoves {
+			return bestValue, tbZeroingBestMove, nil
+		}
+		return bestValue, tbOK, nil
+	}
+
+	return value, tbOK, nil
+}
+
+//dtz of the move right before a capture or pawn move that gets wdl
+func dtzBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case WDLWin:
+		return 1
+	case WDLCursedWin:
+		return 101
+	case WDLBlessedLoss:
+		return -101
+	case WDLLoss:
+		return -1
+	}
+	return 0
+}
+
+func (b Board) probeDTZ() (int, error) {
+	wdl, state, err := b.searchWDL(true)
+	if err != nil {
+		return 0, err
+	}
+
+	//dtz tables don't store draws
+	if wdl == WDLDraw {
+		return 0, nil
+	}
+
+	if state == tbZeroingBestMove {
+		return dtzBeforeZeroing(wdl), nil
+	}
+
+	dtz, state, err := b.probeTable(true, wdl)
+	if err != nil {
+		return 0, err
+	}
+
+	if state != tbChangeSTM {
+		if wdl == WDLBlessedLoss || wdl == WDLCursedWin {
+			dtz += 100
+		}
+		if wdl < 0 {
+			return -dtz, nil
+		}
+		return dtz, nil
+	}
+
+	//the table is for the other side to move, so we look one ply ahead
+	//and take the best of the moves that keep the result
+	minDTZ := 0xffff
+	for _, m := range b.moves(b.turn) {
+		zeroing := b.isCapture(m) || m.piece == pawn
+
+		child := b.Clone()
+		child.move(m)
+
+		if zeroing {
+			//for these we want the dtz from before the move
+			childWDL, _, err := child.searchWDL(false)
+			if err != nil {
+				return 0, err
+			}
+			dtz = -dtzBeforeZeroing(childWDL)
+		} else {
+			dtz, err = child.probeDTZ()
+			if err != nil {
+				return 0, err
+			}
+			dtz = -dtz
+		}
+
+		if dtz == 1 && child.IsCheckmate() {
+			minDTZ = 1
+		}
+
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+
+		if dtz < minDTZ && sign(dtz) == sign(int(wdl)) {
+			minDTZ = dtz
+		}
+	}
+
+	//no legal moves means we've been mated
+	if minDTZ == 0xffff {
+		return -1, nil
+	}
+
+	return minDTZ, nil
+}
+
+func sign(n int) int {
+	if n > 0 {
+		return 1
+	}
+	if n < 0 {
+		return -1
+	}
+	return 0
+}
+
+//looks up the table for the board. flipped means the board has the colors
+//the other way around from the table's name
+func (b Board) findTable(dtz bool) (*tbTable, bool, error) {
+	whiteSide, blackSide := b.materialSide(white), b.materialSide(black)
+
+	tablebases.RLock()
+	tables := tablebases.wdl
+	if dtz {
+		tables = tablebases.dtz
+	}
+	table, found := tables[whiteSide+"v"+blackSide]
+	flipped := false
+	if !found {
+		table, found = tables[blackSide+"v"+whiteSide]
+		flipped = true
+	}
+	tablebases.RUnlock()
+
+	if !found {
+		return nil, false, errNoTable
+	}
+
+	return table, flipped, table.load()
+}
+
+//probeTable reads the value stored for the position. for dtz tables wdl is the
+//result the caller already knows, it's needed to make sense of the number
+func (b Board) probeTable(dtz bool, wdl WDL) (int, tbState, error) {
+	//kings alone, there's no file for this
+	if len(b.pieces) == 2 {
+		return int(WDLDraw), tbOK, nil
+	}
+
+	table, flipped, err := b.findTable(dtz)
+	if err != nil {
+		return 0, tbOK, err
+	}
+
+	return table.probe(b, flipped, wdl)
+}
+
+//tbTable is a single .rtbw or .rtbz file
+type tbTable struct {
+	name string
+	path string
+	dtz  bool
+
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	symmetric       bool
+	//pawns of the leading color and of the other one
+	pawnCount [2]int
+
+	loaded  sync.Once
+	loadErr error
+	data    []byte
+	//[side to move][file of the leading pawn]
+	items [2][4]*tbPairs
+	//where the dtz value map starts
+	dtzMap int
+}
+
+//the compressed values of a table for one side to move and one file
+type tbPairs struct {
+	flags       byte
+	pieces      [tbMaxPieces]byte
+	groupLen    [tbMaxPieces + 1]int
+	groupIdx    [tbMaxPieces + 1]uint64
+	mapIdx      [4]int
+	singleVal   int
+	minSymLen   int
+	maxSymLen   int
+	base64      []uint64
+	symlen      []int
+	lowestSym   int
+	btree       int
+	blockSize   uint64
+	span        uint64
+	numBlocks   int
+	blockLenPad int
+	sparseSize  int
+	blockLens   int
+	sparse      int
+	blocks      int
+}
+
+func newTBTable(name, path string, dtz bool) (*tbTable, error) {
+	sides := strings.Split(name, "v")
+	if len(sides) != 2 || !strings.HasPrefix(sides[0], "K") || !strings.HasPrefix(sides[1], "K") {
+		return nil, fmt.Errorf("%s isn't a table name", name)
+	}
+
+	table := &tbTable{name: name, path: path, dtz: dtz, symmetric: sides[0] == sides[1]}
+
+	var counts [2][6]int
+	for i, side := range sides {
+		for _, letter := range []byte(side) {
+			pt, err := pieceTypeFromFen(letter)
+			if err != nil {
+				return nil, err
+			}
+			counts[i][pt]++
+			table.pieceCount++
+		}
+	}
+
+	if table.pieceCount > tbMaxPieces {
+		return nil, fmt.Errorf("%s has too many pieces", name)
+	}
+
+	for i := range counts {
+		for pt := pawn; pt < king; pt++ {
+			if counts[i][pt] == 1 {
+				table.hasUniquePieces = true
+			}
+		}
+	}
+
+	//the leading color is the one with less pawns, but it has to have some
+	whitePawns, blackPawns := counts[0][pawn], counts[1][pawn]
+	table.hasPawns = whitePawns+blackPawns > 0
+	if blackPawns == 0 || (whitePawns > 0 && blackPawns >= whitePawns) {
+		table.pawnCount = [2]int{whitePawns, blackPawns}
+	} else {
+		table.pawnCount = [2]int{blackPawns, whitePawns}
+	}
+
+	return table, nil
+}
+
+func (t *tbTable) load() error {
+	t.loaded.Do(func() {
+		data, err := ioutil.ReadFile(t.path)
+		if err != nil {
+			t.loadErr = err
+			return
+		}
+
+		t.loadErr = t.parse(data)
+		if t.loadErr == nil {
+			t.data = data
+		}
+	})
+
+	return t.loadErr
+}
+
+func (t *tbTable) get(stm, file int) *tbPairs {
+	if t.dtz || t.symmetric {
+		stm = 0
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return t.items[stm][file]
+}
+
+//parse reads the header of the file and works out where everything is.
+//a broken file could send us out of bounds anywhere, so that becomes an error
+func (t *tbTable) parse(data []byte) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("%s is broken", t.path)
+		}
+	}()
+
+	magic := wdlMagic
+	if t.dtz {
+		magic = dtzMagic
+	}
+	if len(data) < 5 || [4]byte{data[0], data[1], data[2], data[3]} != magic {
+		return fmt.Errorf("%s isn't a syzygy table", t.path)
+	}
+
+	pos := 4
+	if (data[pos]&2 != 0) != t.hasPawns {
+		return fmt.Errorf("%s doesn't match its name", t.path)
+	}
+	pos++
+
+	sides := 1
+	if !t.dtz && !t.symmetric {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	//pawns on both sides
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f] = &tbPairs{}
+		}
+
+		order := [2][2]int{{int(data[pos] & 0xf), 0xf}, {int(data[pos] >> 4), 0xf}}
+		if pp {
+			order[0][1] = int(data[pos+1] & 0xf)
+			order[1][1] = int(data[pos+1] >> 4)
+			pos++
+		}
+		pos++
+
+		for k := 0; k < t.pieceCount; k++ {
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.items[i][f].pieces[k] = data[pos] & 0xf
+				} else {
+					t.items[i][f].pieces[k] = data[pos] >> 4
+				}
+			}
+			pos++
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.items[i][f], order[i], f)
+		}
+	}
+
+	pos += pos & 1
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = t.items[i][f].setSizes(data, pos)
+		}
+	}
+
+	if t.dtz {
+		pos = t.setDTZMap(data, pos, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].sparse = pos
+			pos += t.items[i][f].sparseSize * 6
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].blockLens = pos
+			pos += (t.items[i][f].numBlocks + t.items[i][f].blockLenPad) * 2
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			pos = (pos + 0x3f) &^ 0x3f
+			t.items[i][f].blocks = pos
+			pos += t.items[i][f].numBlocks * int(t.items[i][f].blockSize)
+		}
+	}
+
+	if pos > len(data) {
+		return fmt.Errorf("%s is too short", t.path)
+	}
+
+	return nil
+}
+
+//the pieces are split in groups that get encoded together: the leading group
+//(the kings, three unique pieces or the leading pawns), the other pawns, and
+//every set of identical pieces. the order of the groups in the index is stored
+//in the file
+func (t *tbTable) setGroups(d *tbPairs, order [2]int, file int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	d.groupLen[0] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k == order[0] {
+			d.groupIdx[0] = idx
+			if t.hasPawns {
+				idx *= leadPawnsSize[d.groupLen[0]][file]
+			} else if t.hasUniquePieces {
+				idx *= 31332
+			} else {
+				idx *= 462
+			}
+		} else if k == order[1] {
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		} else {
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+//reads the description of the huffman code and the pairs
+func (d *tbPairs) setSizes(data []byte, pos int) int {
+	d.flags = data[pos]
+	pos++
+
+	if d.flags&tbFlagSingleValue != 0 {
+		d.singleVal = int(data[pos])
+		return pos + 1
+	}
+
+	//the groups end with a zero, and the index there is the size of the table
+	size := uint64(0)
+	for i := range d.groupLen {
+		if d.groupLen[i] == 0 {
+			size = d.groupIdx[i]
+			break
+		}
+	}
+
+	d.blockSize = 1 << data[pos]
+	d.span = 1 << data[pos+1]
+	d.sparseSize = int((size + d.span - 1) / d.span)
+	d.blockLenPad = int(data[pos+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[pos+3:]))
+	d.maxSymLen = int(data[pos+7])
+	d.minSymLen = int(data[pos+8])
+	pos += 9
+
+	//canonical huffman: longer codes have smaller values, so base64[i] is the
+	//smallest 64 bit padded code of length minSymLen+i
+	d.lowestSym = pos
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowestSymbol(data, i)) - uint64(d.lowestSymbol(data, i+1))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+	pos += len(d.base64) * 2
+
+	symbols := int(binary.LittleEndian.Uint16(data[pos:]))
+	pos += 2
+	d.btree = pos
+
+	//every symbol is either a value or a pair of symbols. symlen is how many
+	//values (minus one) a symbol expands to
+	d.symlen = make([]int, symbols)
+	visited := make([]bool, symbols)
+	for sym := 0; sym < symbols; sym++ {
+		if !visited[sym] {
+			d.symlen[sym] = d.setSymlen(data, sym, visited)
+		}
+	}
+
+	return pos + symbols*3 + symbols&1
+}
+
+func (d *tbPairs) lowestSymbol(data []byte, i int) uint16 {
+	return binary.LittleEndian.Uint16(data[d.lowestSym+2*i:])
+}
+
+//every node of the tree is 12 bits for the left symbol and 12 for the right
+func (d *tbPairs) pair(data []byte, sym int) (int, int) {
+	lr := data[d.btree+3*sym:]
+	left := int(lr[1]&0xf)<<8 | int(lr[0])
+	right := int(lr[2])<<4 | int(lr[1]>>4)
+	return left, right
+}
+
+func (d *tbPairs) setSymlen(data []byte, sym int, visited []bool) int {
+	visited[sym] = true
+
+	left, right := d.pair(data, sym)
+	if right == 0xfff {
+		return 0
+	}
+
+	if !visited[left] {
+		d.symlen[left] = d.setSymlen(data, left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = d.setSymlen(data, right, visited)
+	}
+
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+//dtz tables can store their values through a map, to fit in fewer bits
+func (t *tbTable) setDTZMap(data []byte, pos, maxFile int) int {
+	t.dtzMap = pos
+
+	for f := 0; f <= maxFile; f++ {
+		d := t.items[0][f]
+		if d.flags&tbFlagMapped == 0 {
+			continue
+		}
+
+		if d.flags&tbFlagWide != 0 {
+			pos += pos & 1
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (pos-t.dtzMap)/2 + 1
+				pos += 2*int(binary.LittleEndian.Uint16(data[pos:])) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = pos - t.dtzMap + 1
+				pos += int(data[pos]) + 1
+			}
+		}
+	}
+
+	return pos + pos&1
+}
+
+//finds the value at index idx: the sparse index gets us close to the right
+//block, then we walk the huffman codes until we get to the symbol that
+//has it and go down the pairs
+func (d *tbPairs) decompress(data []byte, idx uint64) int {
+	if d.flags&tbFlagSingleValue != 0 {
+		return d.singleVal
+	}
+
+	k := idx / d.span
+	entry := data[d.sparse+6*int(k):]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:]))
+
+	offset += int(idx%d.span) - int(d.span/2)
+
+	blockLength := func(i int) int {
+		return int(binary.LittleEndian.Uint16(data[d.blockLens+2*i:]))
+	}
+	for offset < 0 {
+		block--
+		offset += blockLength(block) + 1
+	}
+	for offset > blockLength(block) {
+		offset -= blockLength(block) + 1
+		block++
+	}
+
+	ptr := d.blocks + block*int(d.blockSize)
+	buf64 := binary.BigEndian.Uint64(data[ptr:])
+	ptr += 8
+	buf64Size := 64
+
+	var sym int
+	for {
+		length := 0
+		for buf64 < d.base64[length] {
+			length++
+		}
+
+		sym = int((buf64 - d.base64[length]) >> uint(64-length-d.minSymLen))
+		sym += int(d.lowestSymbol(data, length))
+
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+
+		offset -= d.symlen[sym] + 1
+		length += d.minSymLen
+		buf64 <<= uint(length)
+		buf64Size -= length
+
+		if buf64Size <= 32 {
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(data[ptr:])) << uint(64-buf64Size)
+			ptr += 4
+		}
+	}
+
+	for d.symlen[sym] != 0 {
+		left, right := d.pair(data, sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = right
+		}
+	}
+
+	left, _ := d.pair(data, sym)
+	return left
+}
+
+//the piece codes the files use: 1 to 6 for white pawn to king, 9 to 14 for black
+func tbPieceCode(p piece) byte {
+	code := byte(p.pieceType) + 1
+	if p.color == black {
+		code += 8
+	}
+	return code
+}
+
+//probe works out the index of the position in the table and reads the value
+func (t *tbTable) probe(b Board, flipped bool, wdl WDL) (value int, state tbState, err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("%s is broken", t.path)
+		}
+	}()
+
+	//the tables are stored with white as the side named first, and symmetric
+	//ones only with white to move: everything else gets its colors swapped
+	//and the board turned upside down
+	symmetricBlackToMove := t.symmetric && b.turn == black
+	flip := symmetricBlackToMove || flipped
+	var flipColor byte
+	flipSquares := 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+	}
+	stm := 0
+	if flip != (b.turn == black) {
+		stm = 1
+	}
+
+	squares := make([]int, 0, len(b.pieces))
+	pieces := make([]byte, 0, len(b.pieces))
+	leadPawnsCount := 0
+	tbFile := 0
+
+	leadingColor := color(white)
+	if t.hasPawns {
+		//the first piece of every pawn table is a pawn of the leading color
+		first := t.get(0, 0).pieces[0] ^ flipColor
+		leadingColor = first < 8
+
+		for _, p := range b.pieces {
+			if p.pieceType == pawn && p.color == leadingColor {
+				squares = append(squares, p.position.index()^flipSquares)
+				pieces = append(pieces, tbPieceCode(p)^flipColor)
+			}
+		}
+		sort.Ints(squares)
+		leadPawnsCount = len(squares)
+
+		//the leading pawn is the one nearest the edge, and the lowest one of those
+		lead := 0
+		for i := range squares {
+			if mapPawns[squares[i]] > mapPawns[squares[lead]] {
+				lead = i
+			}
+		}
+		squares[0], squares[lead] = squares[lead], squares[0]
+
+		tbFile = squares[0] % 8
+		if tbFile > 3 {
+			tbFile = 7 - tbFile
+		}
+	}
+
+	d := t.get(stm, tbFile)
+	if t.dtz && int(d.flags&tbFlagSTM) != stm && !(t.symmetric && !t.hasPawns) {
+		return 0, tbChangeSTM, nil
+	}
+
+	others := make([]piece, 0, len(b.pieces))
+	for _, p := range b.pieces {
+		if !t.hasPawns || p.pieceType != pawn || p.color != leadingColor {
+			others = append(others, p)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].position.index() < others[j].position.index() })
+	for _, p := range others {
+		squares = append(squares, p.position.index()^flipSquares)
+		pieces = append(pieces, tbPieceCode(p)^flipColor)
+	}
+
+	size := len(squares)
+
+	//put the pieces in the same order as the table
+	for i := leadPawnsCount; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	//the leading piece always ends up on the a-d files
+	if squares[0]%8 > 3 {
+		for i := range squares {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = leadPawnIdx[leadPawnsCount][squares[0]]
+		rest := squares[1:leadPawnsCount]
+		sort.SliceStable(rest, func(i, j int) bool { return mapPawns[rest[i]] < mapPawns[rest[j]] })
+		for i := 1; i < leadPawnsCount; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		idx = t.encodeLeadingPieces(d, squares)
+	}
+
+	idx *= d.groupIdx[0]
+
+	groupStart := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[groupStart : groupStart+d.groupLen[next]]
+		sort.Ints(group)
+
+		var n uint64
+		for i, square := range group {
+			//squares taken by the groups before this one don't count
+			adjust := 0
+			for _, taken := range squares[:groupStart] {
+				if square > taken {
+					adjust++
+				}
+			}
+
+			free := square - adjust
+			if remainingPawns {
+				free -= 8
+			}
+			n += binomial[i+1][free]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		groupStart += d.groupLen[next]
+	}
+
+	value = d.decompress(t.data, idx)
+	if !t.dtz {
+		return value - 2, tbOK, nil
+	}
+
+	return t.mapDTZ(tbFile, value, wdl), tbOK, nil
+}
+
+//without pawns the board can also be flipped vertically and along the
+//diagonal, so the leading pieces fit in the a1-d1-d4 triangle
+func (t *tbTable) encodeLeadingPieces(d *tbPairs, squares []int) uint64 {
+	if squares[0]/8 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+
+	for i := 0; i < d.groupLen[0]; i++ {
+		if offDiagonal(squares[i]) == 0 {
+			continue
+		}
+
+		if offDiagonal(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	adjust1 := 0
+	if squares[1] > squares[0] {
+		adjust1 = 1
+	}
+	adjust2 := 0
+	if squares[2] > squares[0] {
+		adjust2++
+	}
+	if squares[2] > squares[1] {
+		adjust2++
+	}
+
+	rank := func(square int) int { return square / 8 }
+
+	switch {
+	case offDiagonal(squares[0]) != 0:
+		return uint64((mapA1D1D4[squares[0]]*63+(squares[1]-adjust1))*62 + squares[2] - adjust2)
+	case offDiagonal(squares[1]) != 0:
+		return uint64((6*63+rank(squares[0])*28+mapB1H1H7[squares[1]])*62 + squares[2] - adjust2)
+	case offDiagonal(squares[2]) != 0:
+		return uint64(6*63*62 + 4*28*62 + rank(squares[0])*7*28 + (rank(squares[1])-adjust1)*28 + mapB1H1H7[squares[2]])
+	default:
+		return uint64(6*63*62 + 4*28*62 + 4*7*28 + rank(squares[0])*7*6 + (rank(squares[1])-adjust1)*6 + (rank(squares[2]) - adjust2))
+	}
+}
+
+//turns the raw dtz value into plies
+func (t *tbTable) mapDTZ(file, value int, wdl WDL) int {
+	wdlMap := [...]int{1, 3, 0, 2, 0}
+	d := t.get(0, file)
+
+	if d.flags&tbFlagMapped != 0 {
+		i := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&tbFlagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(t.data[t.dtzMap+2*i:]))
+		} else {
+			value = int(t.data[t.dtzMap+i])
+		}
+	}
+
+	if (wdl == WDLWin && d.flags&tbFlagWinPlies == 0) ||
+		(wdl == WDLLoss && d.flags&tbFlagLossPlies == 0) ||
+		wdl == WDLCursedWin || wdl == WDLBlessedLoss {
+		value *= 2
+	}
+
+	return value + 1
+}
+
+//positive above the a1-h8 diagonal, negative below
+func offDiagonal(square int) int {
+	return square/8 - square%8
+}
+
+//tables to turn squares into indexes, they're the same ones the generator uses
+var (
+	binomial      [6][64]uint64
+	mapB1H1H7     [64]int
+	mapA1D1D4     [64]int
+	mapKK         [10][64]int
+	mapPawns      [64]int
+	leadPawnIdx   [6][64]uint64
+	leadPawnsSize [6][4]uint64
+)
+
+func init() {
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offDiagonal(s) < 0 {
+			mapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	//the a1-d1-d4 triangle, with the diagonal at the end
+	code = 0
+	diagonal := make([]int, 0, 4)
+	for s := 0; s < 28; s++ {
+		if offDiagonal(s) < 0 && s%8 <= 3 {
+			mapA1D1D4[s] = code
+			code++
+		} else if offDiagonal(s) == 0 && s%8 <= 3 {
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		mapA1D1D4[s] = code
+		code++
+	}
+
+	//the 462 ways to put the two kings with the first one in the triangle.
+	//if the first one is on the diagonal the other one can't be above it
+	type kingPair struct{ idx, square int }
+	bothOnDiagonal := make([]kingPair, 0)
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 < 28; s1++ {
+			if mapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue
+			}
+
+			for s2 := 0; s2 < 64; s2++ {
+				dx, dy := s1%8-s2%8, s1/8-s2/8
+				if dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 {
+					continue
+				}
+
+				if offDiagonal(s1) == 0 && offDiagonal(s2) > 0 {
+					continue
+				}
+				if offDiagonal(s1) == 0 && offDiagonal(s2) == 0 {
+					bothOnDiagonal = append(bothOnDiagonal, kingPair{idx, s2})
+					continue
+				}
+
+				mapKK[idx][s2] = code
+				code++
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		mapKK[p.idx][p.square] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	//pawns go from a2 (47) down, the edge files first and then inwards,
+	//so the leading pawn is the one with the highest value
+	available := 47
+	for leadPawns := 1; leadPawns <= 5; leadPawns++ {
+		for f := 0; f < 4; f++ {
+			idx := uint64(0)
+			for r := 1; r <= 6; r++ {
+				square := r*8 + f
+				if leadPawns == 1 {
+					mapPawns[square] = available
+					available--
+					mapPawns[square^7] = available
+					available--
+				}
+				leadPawnIdx[leadPawns][square] = idx
+				idx += binomial[leadPawns-1][mapPawns[square]]
+			}
+			leadPawnsSize[leadPawns][f] = idx
+		}
+	}
+}
+
+//tablebase wins score less than any mate the search can find, so it still
+//goes for a mate when it sees one
+const tbWinScore = mateScore - 2*maxSearchDepth
+
+func tablebaseScore(wdl WDL, ply int) Score {
+	switch wdl {
+	case WDLWin:
+		return tbWinScore - Score(ply)
+	case WDLLoss:
+		return -tbWinScore + Score(ply)
+	}
+	//the fifty move rule makes these draws
+	return 0
+}
+
+//tablebaseRootMoves keeps the moves that get the best result the tables know of:
+//the quickest win that still beats the fifty move rule, or the slowest loss.
+//if the tables don't have the position it gives back all the moves
+func (b Board) tablebaseRootMoves(moves []move) []move {
+	if b.canProbe() != nil {
+		return moves
+	}
+
+	ranks := make([]int, len(moves))
+	for i, m := range moves {
+		child := b.Clone()
+		child.move(m)
+
+		var dtz int
+		if b.isCapture(m) || m.piece == pawn {
+			wdl, _, err := child.searchWDL(false)
+			if err != nil {
+				return moves
+			}
+			dtz = dtzBeforeZeroing(-wdl)
+		} else if child.IsCheckmate() {
+			dtz = 1
+		} else {
+			childDTZ, err := child.probeDTZ()
+			if err != nil {
+				return moves
+			}
+			dtz = -childDTZ + sign(-childDTZ)
+		}
+
+		ranks[i] = tablebaseRank(dtz, b.halfMoves)
+	}
+
+	best := ranks[0]
+	for _, rank := range ranks {
+		if rank > best {
+			best = rank
+		}
+	}
+
+	kept := make([]move, 0, len(moves))
+	for i, m := range moves {
+		if ranks[i] == best {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+//bigger is better. wins that get caught by the fifty move rule are still
+//better than draws, since the other side could go wrong
+func tablebaseRank(dtz, halfMoves int) int {
+	switch {
+	case dtz > 0 && dtz+halfMoves <= 100:
+		return 2000 - dtz
+	case dtz > 0:
+		return 1000 - dtz
+	case dtz < 0 && halfMoves-dtz > 100:
+		return -1000 - dtz
+	case dtz < 0:
+		return -2000 - dtz
+	}
+	return 0
+}
@@ -0,0 +1,256 @@
+package amatriciana
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//every way to put the leading pieces on the board has to end up on an index,
+//and the indexes have to fill the whole range without holes
+func TestTablebaseLeadingPieces(t *testing.T) {
+	table := &tbTable{}
+	pairs := &tbPairs{}
+	pairs.groupLen[0] = 2
+
+	kings := make(map[uint64]bool)
+	for s0 := 0; s0 < 64; s0++ {
+		for s1 := 0; s1 < 64; s1++ {
+			dx, dy := s0%8-s1%8, s0/8-s1/8
+			if dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1 {
+				continue
+			}
+
+			squares := []int{s0, s1}
+			if squares[0]%8 > 3 {
+				squares[0], squares[1] = squares[0]^7, squares[1]^7
+			}
+			kings[table.encodeLeadingPieces(pairs, squares)] = true
+		}
+	}
+	checkIndexes(t, "kings", kings, 462)
+
+	table.hasUniquePieces = true
+	pairs.groupLen[0] = 3
+
+	unique := make(map[uint64]bool)
+	for s0 := 0; s0 < 64; s0++ {
+		for s1 := 0; s1 < 64; s1++ {
+			for s2 := 0; s2 < 64; s2++ {
+				if s0 == s1 || s0 == s2 || s1 == s2 {
+					continue
+				}
+
+				squares := []int{s0, s1, s2}
+				if squares[0]%8 > 3 {
+					for i := range squares {
+						squares[i] ^= 7
+					}
+				}
+				unique[table.encodeLeadingPieces(pairs, squares)] = true
+			}
+		}
+	}
+	checkIndexes(t, "unique pieces", unique, 31332)
+}
+
+func checkIndexes(t *testing.T, what string, indexes map[uint64]bool, size uint64) {
+	if uint64(len(indexes)) != size {
+		t.Errorf("expected %d indexes for the %s, got %d", size, what, len(indexes))
+	}
+	for idx := range indexes {
+		if idx >= size {
+			t.Errorf("index %d for the %s is out of range", idx, what)
+		}
+	}
+}
+
+//a tiny table: 9 values in two blocks, with a symbol that stands for a pair of values
+func TestTablebaseDecompress(t *testing.T) {
+	data := []byte{
+		0,          //flags
+		6,          //64 byte blocks
+		2,          //a sparse entry every 4 values
+		0,          //no block length padding
+		2, 0, 0, 0, //blocks
+		2, 1, //longest and shortest code
+		2, 0, 0, 0, //lowest symbols for codes of length 1 and 2
+		3, 0, //symbols
+		5, 0xf0, 0xff, //0: the value 5
+		7, 0xf0, 0xff, //1: the value 7
+		0, 0x10, 0x00, //2: the pair 0 1
+		0,
+	}
+
+	pairs := &tbPairs{}
+	pairs.groupIdx[0] = 9
+	if pos := pairs.setSizes(data, 0); pos != len(data) {
+		t.Fatal("setSizes stopped at", pos, "instead of", len(data))
+	}
+
+	pairs.sparse = len(data)
+	for _, entry := range [][2]int{{0, 2}, {0, 6}, {1, 2}} {
+		var sparse [6]byte
+		binary.LittleEndian.PutUint32(sparse[:], uint32(entry[0]))
+		binary.LittleEndian.PutUint16(sparse[4:], uint16(entry[1]))
+		data = append(data, sparse[:]...)
+	}
+
+	pairs.blockLens = len(data)
+	data = append(data, 6, 0, 1, 0)
+
+	//codes are 1 for the pair, 00 for 5 and 01 for 7
+	pairs.blocks = len(data)
+	blocks := make([]byte, 128)
+	blocks[0] = 0x8b //1 00 01 01 1
+	blocks[64] = 0x00
+	data = append(data, blocks...)
+
+	expected := []int{5, 7, 5, 7, 7, 5, 7, 5, 5}
+	for idx, value := range expected {
+		if got := pairs.decompress(data, uint64(idx)); got != value {
+			t.Errorf("expected %d at %d, got %d", value, idx, got)
+		}
+	}
+}
+
+//writes KRvK tables where every position has the same value: white to move
+//wins, black to move loses and the dtz is always 7 plies
+func writeSingleValueTables(t *testing.T, dir string) {
+	header := []byte{
+		0x01,             //split, no pawns
+		0x00,             //order
+		0x66, 0x44, 0xee, //white king, white rook, black king
+		0,
+	}
+
+	wdl := append(append(wdlMagic[:0:0], wdlMagic[:]...), header...)
+	wdl = append(wdl, tbFlagSingleValue, byte(WDLWin+2), tbFlagSingleValue, byte(WDLLoss+2))
+	wdl = append(wdl, make([]byte, 64-len(wdl))...)
+
+	dtz := append(append(dtzMagic[:0:0], dtzMagic[:]...), header...)
+	dtz = append(dtz, tbFlagSingleValue, 3)
+	dtz = append(dtz, make([]byte, 64-len(dtz))...)
+
+	for name, data := range map[string][]byte{"KRvK.rtbw": wdl, "KRvK.rtbz": dtz} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProbeSingleValueTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syzygy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeSingleValueTables(t, dir)
+	err = SetSyzygyPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetSyzygyPath("")
+
+	if SyzygyMaxPieces() != 3 {
+		t.Fatal("expected tables up to 3 pieces, got", SyzygyMaxPieces())
+	}
+
+	tests := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"8/8/8/4k3/8/8/8/R3K3 w - - 0 1", WDLWin, 7},
+		//every king move leads to a position where white needs 7 more
+		{"8/8/8/4k3/8/8/8/R3K3 b - - 0 1", WDLLoss, -8},
+		//the colors get swapped to use the same table
+		{"r3k3/8/8/8/4K3/8/8/8 w - - 0 1", WDLLoss, -8},
+		//black takes the rook
+		{"8/8/8/8/8/8/3k4/4R1K1 b - - 0 1", WDLDraw, 0},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wdl, err := board.ProbeWDL()
+		if err != nil {
+			t.Fatal(test.fen, err)
+		}
+		if wdl != test.wdl {
+			t.Errorf("%s: expected %v, got %v", test.fen, test.wdl, wdl)
+		}
+
+		dtz, err := board.ProbeDTZ()
+		if err != nil {
+			t.Fatal(test.fen, err)
+		}
+		if dtz != test.dtz {
+			t.Errorf("%s: expected dtz %d, got %d", test.fen, test.dtz, dtz)
+		}
+	}
+
+	board, _ := BoardFromFEN("4k3/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	if _, err := board.ProbeWDL(); err == nil {
+		t.Error("castling rights should stop the probe")
+	}
+
+	board, _ = BoardFromFEN("8/8/8/4k3/8/8/8/RR2K3 w - - 0 1")
+	if _, err := board.ProbeWDL(); err == nil {
+		t.Error("there's no table for four pieces")
+	}
+}
+
+//only runs if you have the real tables, in the directories in SYZYGY_PATH
+func TestProbeRealTables(t *testing.T) {
+	path := os.Getenv("SYZYGY_PATH")
+	if path == "" {
+		t.Skip("SYZYGY_PATH isn't set")
+	}
+
+	err := SetSyzygyPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetSyzygyPath("")
+
+	tests := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"8/8/8/8/4k3/8/8/4K2Q w - - 0 1", WDLWin, 1},
+		{"8/8/8/8/4k3/8/8/4K2Q b - - 0 1", WDLLoss, -1},
+		{"8/8/8/8/8/3k4/8/3K1B2 w - - 0 1", WDLDraw, 0},
+	}
+
+	for _, test := range tests {
+		board, err := BoardFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wdl, err := board.ProbeWDL()
+		if err != nil {
+			t.Fatal(test.fen, err)
+		}
+		if wdl != test.wdl {
+			t.Errorf("%s: expected %v, got %v", test.fen, test.wdl, wdl)
+		}
+
+		dtz, err := board.ProbeDTZ()
+		if err != nil {
+			t.Fatal(test.fen, err)
+		}
+		if sign(dtz) != test.dtz {
+			t.Errorf("%s: expected a dtz with sign %d, got %d", test.fen, test.dtz, dtz)
+		}
+	}
+}
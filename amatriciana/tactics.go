@@ -0,0 +1,341 @@
+package amatriciana
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+//tactics for teaching: the motifs a position has and the ones a move
+//creates, found on the bitboards with the same pieces as attacks.go.
+//they're the usual textbook shapes, not proof that something wins material
+
+//Motif is a kind of tactic
+type Motif int
+
+const (
+	//Fork is a piece attacking two or more enemy pieces it could win
+	Fork Motif = iota
+	//AbsolutePin is a piece that can't move because its king is behind it
+	AbsolutePin
+	//RelativePin is a piece that shouldn't move because something worth
+	//more than it is behind it
+	RelativePin
+	//Skewer is a valuable piece attacked on a line with something else
+	//behind it, that gets taken once the first one moves away
+	Skewer
+	//DiscoveredAttack is a move getting out of the way of a slider that
+	//then attacks an enemy piece
+	DiscoveredAttack
+	//DiscoveredCheck is a discovered attack on the king
+	DiscoveredCheck
+	//OverloadedDefender is the only defender of two or more attacked pieces,
+	//it can't keep all of them safe
+	OverloadedDefender
+	//HangingPiece is an attacked piece that isn't defended, or that is
+	//attacked by something worth less
+	HangingPiece
+	//BackRankWeakness is a king stuck on its first rank behind its own
+	//pieces, with enemy rooks or queens around to mate it there
+	BackRankWeakness
+)
+
+func (m Motif) String() string {
+	switch m {
+	case Fork:
+		return "fork"
+	case AbsolutePin:
+		return "absolute pin"
+	case RelativePin:
+		return "relative pin"
+	case Skewer:
+		return "skewer"
+	case DiscoveredAttack:
+		return "discovered attack"
+	case DiscoveredCheck:
+		return "discovered check"
+	case OverloadedDefender:
+		return "overloaded defender"
+	case HangingPiece:
+		return "hanging piece"
+	case BackRankWeakness:
+		return "back rank weakness"
+	}
+	return "???"
+}
+
+//Tactic is a motif found on the board
+type Tactic struct {
+	Motif Motif
+	//Color is the side the tactic is good for
+	Color Color
+	//Piece is where the tactic is: the forking piece, the pinning or
+	//skewering slider, the slider a discovered attack uncovers, the
+	//overloaded defender, the hanging piece or the king on the back rank
+	Piece Square
+	//Targets are the pieces it's about: the forked pieces, the pinned
+	//piece and what's behind it, the piece in front and the one behind
+	//in a skewer, what the discovered attack hits, the pieces the
+	//overloaded defender has to defend, who attacks the hanging piece,
+	//and the squares in front of the king that keep it on the back rank
+	Targets SquareSet
+}
+
+func (t Tactic) String() string {
+	return fmt.Sprintf("%v for %v, %v: %v", t.Motif, t.Color, t.Piece, t.Targets)
+}
+
+//FindTactics gives back the tactics both sides have in the position. the
+//discovered attacks need a move, see MoveTactics
+func (b Board) FindTactics() []Tactic {
+	m := b.bitboards()
+	squares := m.pieceTypes()
+
+	var tactics []Tactic
+	for _, col := range [...]color{white, black} {
+		tactics = append(tactics, m.forks(squares, col)...)
+		tactics = append(tactics, m.pinsAndSkewers(squares, col)...)
+		tactics = append(tactics, m.hangingPieces(squares, col)...)
+		tactics = append(tactics, m.overloadedDefenders(squares, col)...)
+		if tactic, found := m.backRankWeakness(col); found {
+			tactics = append(tactics, tactic)
+		}
+	}
+	return tactics
+}
+
+//MoveTactics gives back the tactics a legal move in uci notation creates:
+//the ones the position after it has and the position before it didn't,
+//and the discovered attacks of the move
+func (b Board) MoveTactics(uciMove string) ([]Tactic, error) {
+	m, err := b.parseMove(uciMove)
+	if err != nil {
+		return nil, err
+	}
+	if m.color != b.turn || !containsMove(b.moves(b.turn), m) {
+		return nil, errors.New("illegal move")
+	}
+
+	after := b.clonePosition()
+	after.move(m)
+
+	before := b.FindTactics()
+	var tactics []Tactic
+	for _, tactic := range after.FindTactics() {
+		if !containsTactic(before, tactic) {
+			tactics = append(tactics, tactic)
+		}
+	}
+
+	return append(tactics, b.discoveredAttacks(after, m)...), nil
+}
+
+func containsTactic(tactics []Tactic, t Tactic) bool {
+	for _, other := range tactics {
+		if other == t {
+			return true
+		}
+	}
+	return false
+}
+
+//what's standing on every square. the empty ones look like pawns, nothing
+//asks about them anyway
+func (m attackMap) pieceTypes() [64]pieceType {
+	var squares [64]pieceType
+	for col := range m.pieces {
+		for pt, set := range m.pieces[col] {
+			for ; set != 0; set &= set - 1 {
+				squares[bits.TrailingZeros64(set)] = pieceType(pt)
+			}
+		}
+	}
+	return squares
+}
+
+//the pieces that attack a square, the ones of the same color on it
+//are defending it
+func (m attackMap) defenders(square int, col color) uint64 {
+	return m.attackersOf(square, col, m.occupied)
+}
+
+//a piece of col forks when two or more of the enemy pieces it attacks are
+//worth more than it, aren't defended or are the king
+func (m attackMap) forks(squares [64]pieceType, col color) []Tactic {
+	var tactics []Tactic
+	for set := m.byColor[col.index()]; set != 0; set &= set - 1 {
+		square := bits.TrailingZeros64(set)
+		p := piece{squareXY(square), col, squares[square]}
+
+		var targets uint64
+		for attacked := pieceAttacks(p, m.occupied) & m.byColor[(!col).index()]; attacked != 0; attacked &= attacked - 1 {
+			target := bits.TrailingZeros64(attacked)
+			if squares[target] == king || pieceOrder[squares[target]] > pieceOrder[squares[square]] || m.defenders(target, !col) == 0 {
+				targets |= 1 << uint(target)
+			}
+		}
+
+		if bits.OnesCount64(targets) >= 2 {
+			tactics = append(tactics, Tactic{Fork, col, Square(square), SquareSet(targets)})
+		}
+	}
+	return tactics
+}
+
+//a slider of col, an enemy piece it attacks and another enemy piece right
+//behind it on the same line: a pin if the one behind is worth more, a
+//skewer if the one in front is
+func (m attackMap) pinsAndSkewers(squares [64]pieceType, col color) []Tactic {
+	them := m.byColor[(!col).index()]
+
+	var tactics []Tactic
+	for set := them; set != 0; set &= set - 1 {
+		behind := bits.TrailingZeros64(set)
+		for _, x := range m.blockers(behind, col) {
+			front := int(x.Blocker)
+			if them&(1<<uint(front)) == 0 {
+				continue
+			}
+
+			targets := SquareSet(1<<uint(front) | 1<<uint(behind))
+			switch {
+			case squares[behind] == king:
+				tactics = append(tactics, Tactic{AbsolutePin, col, x.Attacker, targets})
+			case squares[front] == king:
+				tactics = append(tactics, Tactic{Skewer, col, x.Attacker, targets})
+			case pieceOrder[squares[behind]] > pieceOrder[squares[front]]:
+				tactics = append(tactics, Tactic{RelativePin, col, x.Attacker, targets})
+			case pieceOrder[squares[front]] > pieceOrder[squares[behind]] && squares[behind] != pawn:
+				tactics = append(tactics, Tactic{Skewer, col, x.Attacker, targets})
+			}
+		}
+	}
+	return tactics
+}
+
+//the enemy pieces col can take for free, or with something worth less
+func (m attackMap) hangingPieces(squares [64]pieceType, col color) []Tactic {
+	var tactics []Tactic
+	for set := m.byColor[(!col).index()] &^ m.pieces[(!col).index()][king]; set != 0; set &= set - 1 {
+		square := bits.TrailingZeros64(set)
+		attackers := m.attackersOf(square, col, m.occupied)
+		if attackers == 0 {
+			continue
+		}
+
+		cheapest := king
+		for a := attackers; a != 0; a &= a - 1 {
+			if attacker := squares[bits.TrailingZeros64(a)]; pieceOrder[attacker] < pieceOrder[cheapest] {
+				cheapest = attacker
+			}
+		}
+
+		if m.defenders(square, !col) == 0 || pieceOrder[cheapest] < pieceOrder[squares[square]] {
+			tactics = append(tactics, Tactic{HangingPiece, col, Square(square), SquareSet(attackers)})
+		}
+	}
+	return tactics
+}
+
+//an enemy piece that's the only defender of two or more enemy pieces col
+//attacks. a piece that's hanging anyway doesn't count as one of its jobs
+func (m attackMap) overloadedDefenders(squares [64]pieceType, col color) []Tactic {
+	var duties [64]uint64
+	for set := m.byColor[(!col).index()] &^ m.pieces[(!col).index()][king]; set != 0; set &= set - 1 {
+		square := bits.TrailingZeros64(set)
+		attackers := m.attackersOf(square, col, m.occupied)
+		defenders := m.defenders(square, !col)
+		if attackers == 0 || bits.OnesCount64(defenders) != 1 {
+			continue
+		}
+
+		cheaper := false
+		for a := attackers; a != 0; a &= a - 1 {
+			cheaper = cheaper || pieceOrder[squares[bits.TrailingZeros64(a)]] < pieceOrder[squares[square]]
+		}
+		if !cheaper {
+			duties[bits.TrailingZeros64(defenders)] |= 1 << uint(square)
+		}
+	}
+
+	var tactics []Tactic
+	for square, defended := range duties {
+		if bits.OnesCount64(defended) >= 2 {
+			tactics = append(tactics, Tactic{OverloadedDefender, col, Square(square), SquareSet(defended)})
+		}
+	}
+	return tactics
+}
+
+//the enemy king on its first rank with every square in front of it taken by
+//its own pieces or attacked, while col has a rook or a queen and the enemy
+//doesn't have one on that rank
+func (m attackMap) backRankWeakness(col color) (Tactic, bool) {
+	enemy := !col
+	kingSquare, found := m.kingSquare(enemy)
+	if !found || m.pieces[col.index()][rook]|m.pieces[col.index()][queen] == 0 {
+		return Tactic{}, false
+	}
+
+	backRank, forward := 0, 8
+	if enemy == black {
+		backRank, forward = 7, -8
+	}
+	if kingSquare/8 != backRank {
+		return Tactic{}, false
+	}
+	//a rook or a queen on the same rank is there to cover it
+	rank := uint64(0xff) << uint(8*backRank)
+	if (m.pieces[enemy.index()][rook]|m.pieces[enemy.index()][queen])&rank != 0 {
+		return Tactic{}, false
+	}
+
+	var inFront uint64
+	for set := kingAttacks[kingSquare]; set != 0; set &= set - 1 {
+		if square := bits.TrailingZeros64(set); square/8 == backRank+forward/8 {
+			inFront |= 1 << uint(square)
+		}
+	}
+
+	for set := inFront; set != 0; set &= set - 1 {
+		square := bits.TrailingZeros64(set)
+		blocked := m.byColor[enemy.index()]&(1<<uint(square)) != 0
+		if !blocked && m.attackersOf(square, col, m.occupied&^(1<<uint(kingSquare))) == 0 {
+			return Tactic{}, false
+		}
+	}
+
+	return Tactic{BackRankWeakness, col, Square(kingSquare), SquareSet(inFront)}, true
+}
+
+//the sliders of the side moving that mv gets out of the way of, and the
+//enemy pieces they attack now. a discovered attack on a pawn isn't worth
+//telling anyone about
+func (b Board) discoveredAttacks(after Board, mv move) []Tactic {
+	before := b.bitboards()
+	squares := before.pieceTypes()
+	a := after.bitboards()
+	col := mv.color
+	from := mv.from.index()
+
+	var tactics []Tactic
+	for set := before.byColor[(!col).index()]; set != 0; set &= set - 1 {
+		target := bits.TrailingZeros64(set)
+		if squares[target] == pawn || a.byColor[(!col).index()]&(1<<uint(target)) == 0 {
+			continue
+		}
+
+		for _, x := range before.blockers(target, col) {
+			if int(x.Blocker) != from || a.attackersOf(target, col, a.occupied)&x.Attacker.bit() == 0 {
+				continue
+			}
+
+			motif := DiscoveredAttack
+			if squares[target] == king {
+				motif = DiscoveredCheck
+			}
+			tactics = append(tactics, Tactic{motif, col, x.Attacker, SquareSet(1 << uint(target))})
+		}
+	}
+	return tactics
+}
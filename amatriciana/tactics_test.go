@@ -0,0 +1,89 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func findTactic(tactics []Tactic, motif Motif, col Color, square, targets string) bool {
+	for _, t := range tactics {
+		if t.Motif == motif && t.Color == col && t.Piece.String() == square && t.Targets.String() == targets {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFindTactics(t *testing.T) {
+	tests := []struct {
+		name, fen string
+		motif     Motif
+		col       Color
+		square    string
+		targets   string
+	}{
+		{"knight fork", "r3k3/2N5/8/8/8/8/8/4K3 b - - 0 1", Fork, White, "c7", "a8 e8"},
+		{"absolute pin", "4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1", AbsolutePin, Black, "e7", "e1 e2"},
+		{"relative pin", "4k3/8/8/1b6/8/3N4/8/5Q1K w - - 0 1", RelativePin, Black, "b5", "f1 d3"},
+		{"skewer", "8/q7/8/2k5/8/8/8/6BK b - - 0 1", Skewer, White, "g1", "c5 a7"},
+		{"undefended piece", "4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1", HangingPiece, White, "d5", "d1"},
+		{"attacked by a pawn", "4k3/8/4p3/3n4/2P5/8/8/4K3 w - - 0 1", HangingPiece, White, "d5", "c4"},
+		{"overloaded queen", "6k1/4q3/8/2n3b1/8/8/8/2R1K1R1 w - - 0 1", OverloadedDefender, White, "e7", "c5 g5"},
+		{"back rank", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", BackRankWeakness, White, "g8", "f7 g7 h7"},
+	}
+
+	for _, test := range tests {
+		tactics := boardOrFail(t, test.fen).FindTactics()
+		if !findTactic(tactics, test.motif, test.col, test.square, test.targets) {
+			t.Errorf("%s: no %v for %v on %s against %s in %v", test.name, test.motif, test.col, test.square, test.targets, tactics)
+		}
+	}
+
+	quiet := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		//the king has somewhere to go
+		"6k1/5pp1/7p/8/8/8/8/R5K1 w - - 0 1",
+	}
+	for _, fen := range quiet {
+		if tactics := boardOrFail(t, fen).FindTactics(); len(tactics) != 0 {
+			t.Errorf("%s shouldn't have any tactics: %v", fen, tactics)
+		}
+	}
+}
+
+func TestMoveTactics(t *testing.T) {
+	tests := []struct {
+		name, fen, move string
+		motif           Motif
+		square          string
+		targets         string
+	}{
+		{"fork", "r3k3/8/8/1N6/8/8/8/4K3 w - - 0 1", "b5c7", Fork, "c7", "a8 e8"},
+		{"discovered check", "4k3/8/8/8/8/8/4N3/4R1K1 w - - 0 1", "e2c3", DiscoveredCheck, "e1", "e8"},
+		{"discovered attack", "4k3/4q3/8/8/8/8/4N3/4R1K1 w - - 0 1", "e2c3", DiscoveredAttack, "e1", "e7"},
+	}
+
+	for _, test := range tests {
+		board := boardOrFail(t, test.fen)
+		tactics, err := board.MoveTactics(test.move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !findTactic(tactics, test.motif, board.turn, test.square, test.targets) {
+			t.Errorf("%s: no %v on %s against %s in %v", test.name, test.motif, test.square, test.targets, tactics)
+		}
+	}
+
+	//the knight was already forking, so it isn't something the king move did
+	board := boardOrFail(t, "r3k3/2N5/8/8/8/8/8/4K3 w - - 0 1")
+	tactics, err := board.MoveTactics("e1d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tactics) != 0 {
+		t.Errorf("the king move doesn't do anything: %v", tactics)
+	}
+
+	if _, err := board.MoveTactics("e1e3"); err == nil {
+		t.Error("e1e3 isn't legal")
+	}
+}
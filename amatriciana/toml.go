@@ -0,0 +1,276 @@
+package amatriciana
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//just enough toml for the evaluation weights: integers, booleans, plain
+//strings, arrays, inline tables and [table] headers. it gets turned into json
+//so encoding/json can do the rest
+
+type tomlParser struct {
+	text []rune
+	pos  int
+	line int
+}
+
+func tomlToJSON(data []byte) ([]byte, error) {
+	p := &tomlParser{text: []rune(string(data)), line: 1}
+	root := map[string]interface{}{}
+	table := root
+
+	for {
+		p.skipSpace(true)
+		if p.done() {
+			break
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			name, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace(false)
+			if p.done() || p.peek() != ']' {
+				return nil, p.errorf("expected ] after the table name")
+			}
+			p.pos++
+
+			//a.b makes the table b inside a
+			table = root
+			for _, part := range strings.Split(name, ".") {
+				next, ok := table[part].(map[string]interface{})
+				if !ok {
+					next = map[string]interface{}{}
+					table[part] = next
+				}
+				table = next
+			}
+			continue
+		}
+
+		name, value, err := p.keyValue()
+		if err != nil {
+			return nil, err
+		}
+		table[name] = value
+
+		p.skipSpace(false)
+		if !p.done() && p.peek() != '\n' {
+			return nil, p.errorf("expected the end of the line after %s", name)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (p *tomlParser) done() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *tomlParser) peek() rune {
+	return p.text[p.pos]
+}
+
+func (p *tomlParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+//skips spaces and comments, and the ends of lines too if newlines is set
+func (p *tomlParser) skipSpace(newlines bool) {
+	for !p.done() {
+		switch c := p.peek(); {
+		case c == '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.line++
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) key() (string, error) {
+	p.skipSpace(false)
+	start := p.pos
+	for !p.done() {
+		c := p.peek()
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			break
+		}
+		p.pos++
+	}
+
+	if start == p.pos {
+		return "", p.errorf("expected a key")
+	}
+	return string(p.text[start:p.pos]), nil
+}
+
+func (p *tomlParser) keyValue() (string, interface{}, error) {
+	name, err := p.key()
+	if err != nil {
+		return "", nil, err
+	}
+
+	p.skipSpace(false)
+	if p.done() || p.peek() != '=' {
+		return "", nil, p.errorf("expected = after %s", name)
+	}
+	p.pos++
+
+	value, err := p.value()
+	return name, value, err
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	p.skipSpace(false)
+	if p.done() {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.peek() {
+	case '[':
+		p.pos++
+		array := []interface{}{}
+		for {
+			//arrays can go on for more than a line
+			p.skipSpace(true)
+			if p.done() {
+				return nil, p.errorf("the array never ends")
+			}
+			if p.peek() == ']' {
+				p.pos++
+				return array, nil
+			}
+
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+
+			p.skipSpace(true)
+			if !p.done() && p.peek() == ',' {
+				p.pos++
+			} else if p.done() || p.peek() != ']' {
+				return nil, p.errorf("expected , or ] in the array")
+			}
+		}
+
+	case '{':
+		p.pos++
+		table := map[string]interface{}{}
+		p.skipSpace(false)
+		if !p.done() && p.peek() == '}' {
+			p.pos++
+			return table, nil
+		}
+		for {
+			name, value, err := p.keyValue()
+			if err != nil {
+				return nil, err
+			}
+			table[name] = value
+
+			p.skipSpace(false)
+			if p.done() {
+				return nil, p.errorf("the inline table never ends")
+			}
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case '}':
+				p.pos++
+				return table, nil
+			default:
+				return nil, p.errorf("expected , or } in the inline table")
+			}
+		}
+
+	case '"':
+		p.pos++
+		start := p.pos
+		for !p.done() && p.peek() != '"' && p.peek() != '\n' {
+			p.pos++
+		}
+		if p.done() || p.peek() != '"' {
+			return nil, p.errorf("the string never ends")
+		}
+		p.pos++
+		return string(p.text[start : p.pos-1]), nil
+	}
+
+	start := p.pos
+	for !p.done() && strings.IndexRune(" \t\r\n,]}#", p.peek()) < 0 {
+		p.pos++
+	}
+	word := string(p.text[start:p.pos])
+
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	n, err := strconv.ParseInt(strings.Replace(word, "_", "", -1), 0, 64)
+	if err != nil {
+		return nil, p.errorf("%q isn't a number", word)
+	}
+	return n, nil
+}
+
+//writes the fields of the struct v in order, with the names from their json tags
+func encodeTOML(v interface{}) []byte {
+	var out strings.Builder
+
+	value := reflect.ValueOf(v)
+	for i := 0; i < value.NumField(); i++ {
+		name := jsonName(value.Type().Field(i))
+		fmt.Fprintf(&out, "%s = %s\n", name, tomlValue(value.Field(i)))
+	}
+
+	return []byte(out.String())
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func tomlValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = tomlValue(v.Index(i))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+
+	case reflect.Struct:
+		values := make([]string, v.NumField())
+		for i := range values {
+			values[i] = jsonName(v.Type().Field(i)) + " = " + tomlValue(v.Field(i))
+		}
+		return "{ " + strings.Join(values, ", ") + " }"
+
+	case reflect.String:
+		return strconv.Quote(v.String())
+	}
+
+	return fmt.Sprint(v.Interface())
+}
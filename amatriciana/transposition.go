@@ -0,0 +1,130 @@
+package amatriciana
+
+import "sync/atomic"
+
+type boundType uint8
+
+const (
+	exactBound boundType = iota
+	lowerBound
+	upperBound
+)
+
+//TranspositionTable remembers what the search found out about positions it
+//already visited. it can be shared by many searches at the same time without
+//locks: every slot stores the key xor'd with the data, so a slot that two
+//threads wrote at once just doesn't match anything anymore
+type TranspositionTable struct {
+	slots []ttSlot
+}
+
+type ttSlot struct {
+	check uint64
+	data  uint64
+}
+
+type ttEntry struct {
+	score Score
+	depth int
+	bound boundType
+	move  uint16
+}
+
+//NewTranspositionTable makes a table that takes up about megabytes MB
+func NewTranspositionTable(megabytes int) *TranspositionTable {
+	if megabytes < 1 {
+		megabytes = 1
+	}
+
+	return &TranspositionTable{
+		slots: make([]ttSlot, megabytes*1024*1024/16),
+	}
+}
+
+//Clear forgets everything
+func (t *TranspositionTable) Clear() {
+	for i := range t.slots {
+		atomic.StoreUint64(&t.slots[i].check, 0)
+		atomic.StoreUint64(&t.slots[i].data, 0)
+	}
+}
+
+func (t *TranspositionTable) probe(hash uint64) (ttEntry, bool) {
+	slot := &t.slots[hash%uint64(len(t.slots))]
+	check := atomic.LoadUint64(&slot.check)
+	data := atomic.LoadUint64(&slot.data)
+
+	if data == 0 || check^data != hash {
+		return ttEntry{}, false
+	}
+
+	return unpackEntry(data), true
+}
+
+//deeper results for the same position are kept, anything else gets replaced
+func (t *TranspositionTable) store(hash uint64, entry ttEntry) {
+	slot := &t.slots[hash%uint64(len(t.slots))]
+
+	old, found := t.probe(hash)
+	if found && old.depth > entry.depth {
+		return
+	}
+
+	data := packEntry(entry)
+	atomic.StoreUint64(&slot.data, data)
+	atomic.StoreUint64(&slot.check, hash^data)
+}
+
+//score in the top 32 bits, then 8 bits of depth, 8 of bound, 16 of move
+func packEntry(e ttEntry) uint64 {
+	return uint64(uint32(int32(e.score)))<<32 |
+		uint64(uint8(e.depth))<<24 |
+		uint64(e.bound)<<16 |
+		uint64(e.move)
+}
+
+func unpackEntry(data uint64) ttEntry {
+	return ttEntry{
+		score: Score(int32(data >> 32)),
+		depth: int(uint8(data >> 24)),
+		bound: boundType(data >> 16),
+		move:  uint16(data),
+	}
+}
+
+//6 bits for each square and 3 for the promotion, the rest of the move
+//can be worked out from the board
+func (m move) pack() uint16 {
+	var promotesInto uint16
+	if m.moveType == promotion {
+		promotesInto = uint16(m.promotesInto)
+	}
+
+	return uint16(m.from.index()) | uint16(m.to.index())<<6 | promotesInto<<12 | 1<<15
+}
+
+func (m move) matches(packed uint16) bool {
+	return packed != 0 && m.pack() == packed
+}
+
+//mates are stored as distance from the node, not from the root,
+//so they're still right when the same position shows up at another ply
+func scoreToTT(score Score, ply int) Score {
+	if score > mateScore-maxSearchDepth {
+		return score + Score(ply)
+	}
+	if score < -mateScore+maxSearchDepth {
+		return score - Score(ply)
+	}
+	return score
+}
+
+func scoreFromTT(score Score, ply int) Score {
+	if score > mateScore-maxSearchDepth {
+		return score - Score(ply)
+	}
+	if score < -mateScore+maxSearchDepth {
+		return score + Score(ply)
+	}
+	return score
+}
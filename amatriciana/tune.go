@@ -0,0 +1,201 @@
+package amatriciana
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//texel tuning: the evaluation of a quiet position, squashed between 0 and 1,
+//should be the result of the game it comes from. the weights get nudged one
+//at a time for as long as that makes the squared error go down
+
+//TuningPosition is a quiet position with the result of its game:
+//1 if white won, 0.5 for a draw and 0 if black won
+type TuningPosition struct {
+	Board  Board
+	Result float64
+}
+
+//ParseTuningPosition reads an epd line with the result in a c9 opcode like
+//c9 "1-0"; or in brackets at the end like [1-0] or [0.5]. lines written by
+//TrainingPosition.String work too, the score is ignored
+func ParseTuningPosition(line string) (TuningPosition, error) {
+	if strings.Count(line, "|") == 2 {
+		p, err := ParseTrainingPosition(line)
+		return TuningPosition{p.Board, p.Result}, err
+	}
+
+	//the brackets aren't epd, they go before the rest gets parsed
+	var result string
+	if at := strings.LastIndex(line, "["); at >= 0 {
+		result = strings.TrimSuffix(strings.TrimSpace(line[at+1:]), "]")
+		line = line[:at]
+	}
+
+	e, err := ParseEPD(line)
+	if err != nil {
+		return TuningPosition{}, err
+	}
+	if c9, found := e.Operation("c9"); found && result == "" && len(c9) == 1 {
+		result = c9[0]
+	}
+	if result == "" {
+		return TuningPosition{}, fmt.Errorf("no result in %q", line)
+	}
+
+	var score float64
+	switch result = strings.TrimSpace(result); result {
+	case "1-0":
+		score = 1
+	case "0-1":
+		score = 0
+	case "1/2-1/2":
+		score = 0.5
+	default:
+		score, err = strconv.ParseFloat(result, 64)
+		if err != nil || score < 0 || score > 1 {
+			return TuningPosition{}, fmt.Errorf("%q isn't a result", result)
+		}
+	}
+
+	return TuningPosition{e.Board, score}, nil
+}
+
+//TuneOptions says how the tuning goes
+type TuneOptions struct {
+	//Threads is how many goroutines evaluate positions, 0 means one per cpu
+	Threads int
+	//Passes is the most times every weight gets tried, 0 means no limit
+	Passes int
+	//Step is how much a weight changes at a time, in centipawns. 0 means 1
+	Step int
+	//Only are the names of the weights to tune like in EvalParams.List,
+	//all of them if it's empty
+	Only []string
+	//Info gets called after every pass
+	Info func(TuneInfo)
+}
+
+//TuneInfo describes how the tuning is going after a pass
+type TuneInfo struct {
+	Pass int
+	//the mean squared error
+	Error float64
+	//how many weights changed in the pass
+	Changed int
+}
+
+//TuneEvalParams looks for the weights that best predict the results of the
+//positions, starting from params. it gives back the weights, the scaling
+//constant K of the sigmoid it used and the error at the end
+func TuneEvalParams(positions []TuningPosition, params EvalParams, opts TuneOptions) (EvalParams, float64, float64) {
+	if opts.Threads < 1 {
+		opts.Threads = runtime.NumCPU()
+	}
+	if opts.Step < 1 {
+		opts.Step = 1
+	}
+
+	k := bestScalingConstant(positions, evaluateAll(positions, params, opts.Threads))
+	best := tuningError(positions, evaluateAll(positions, params, opts.Threads), k)
+
+	selected := map[string]bool{}
+	for _, name := range opts.Only {
+		selected[strings.ToLower(name)] = true
+	}
+
+	for pass := 1; opts.Passes == 0 || pass <= opts.Passes; pass++ {
+		changed := 0
+
+		for _, param := range params.List() {
+			if len(selected) > 0 && !selected[strings.ToLower(param.Name)] {
+				continue
+			}
+
+			//up first, and down only if up didn't help
+			for _, step := range []int{opts.Step, -2 * opts.Step} {
+				*param.Value += step
+				err := tuningError(positions, evaluateAll(positions, params, opts.Threads), k)
+				if err < best {
+					best = err
+					changed++
+					break
+				}
+				if step < 0 {
+					*param.Value += opts.Step
+				}
+			}
+		}
+
+		if opts.Info != nil {
+			opts.Info(TuneInfo{pass, best, changed})
+		}
+		if changed == 0 {
+			break
+		}
+	}
+
+	return params, k, best
+}
+
+//the evaluation of every position with params, in centipawns from white's point of view
+func evaluateAll(positions []TuningPosition, params EvalParams, threads int) []float64 {
+	evaluator := NewEvaluator(params)
+	scores := make([]float64, len(positions))
+
+	var wg sync.WaitGroup
+	chunk := (len(positions) + threads - 1) / threads
+	for start := 0; start < len(positions); start += chunk {
+		end := start + chunk
+		if end > len(positions) {
+			end = len(positions)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				scores[i] = float64(evaluator.Evaluate(positions[i].Board))
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	return scores
+}
+
+//what a score says the result should be, between 0 and 1
+func winProbability(score, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*score/400))
+}
+
+func tuningError(positions []TuningPosition, scores []float64, k float64) float64 {
+	if len(positions) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for i, p := range positions {
+		diff := p.Result - winProbability(scores[i], k)
+		total += diff * diff
+	}
+	return total / float64(len(positions))
+}
+
+//the error only has one minimum in K, so a ternary search finds it
+func bestScalingConstant(positions []TuningPosition, scores []float64) float64 {
+	low, high := 0.01, 5.0
+	for high-low > 1e-4 {
+		a, b := low+(high-low)/3, high-(high-low)/3
+		if tuningError(positions, scores, a) < tuningError(positions, scores, b) {
+			high = b
+		} else {
+			low = a
+		}
+	}
+	return (low + high) / 2
+}
@@ -0,0 +1,87 @@
+package amatriciana
+
+import (
+	"testing"
+)
+
+func TestParseTuningPosition(t *testing.T) {
+	tests := []struct {
+		line   string
+		fen    string
+		result float64
+	}{
+		{`rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - c9 "1/2-1/2";`,
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", 0.5},
+		{`4k3/8/8/8/8/8/4P3/4K3 w - - hmvc 3; c9 "1-0";`, "4k3/8/8/8/8/8/4P3/4K3 w - - 3 1", 1},
+		{"4k3/4p3/8/8/8/8/8/4K3 b - - 12 40 [0-1]", "4k3/4p3/8/8/8/8/8/4K3 b - - 12 40", 0},
+		{"4k3/4p3/8/8/8/8/8/4K3 b - - 0 1 [0.5]", "4k3/4p3/8/8/8/8/8/4K3 b - - 0 1", 0.5},
+		{"4k3/4p3/8/8/8/8/8/4K3 b - - 4 9 | -80 | 0.0", "4k3/4p3/8/8/8/8/8/4K3 b - - 4 9", 0},
+	}
+
+	for _, test := range tests {
+		position, err := ParseTuningPosition(test.line)
+		if err != nil {
+			t.Fatal(test.line, err)
+		}
+		if position.Board.FEN() != test.fen || position.Result != test.result {
+			t.Errorf("%s: expected %s %v, got %s %v", test.line, test.fen, test.result, position.Board.FEN(), position.Result)
+		}
+	}
+
+	for _, line := range []string{"4k3/8/8/8/8/8/4P3/4K3 w - -", `4k3/8/8/8/8/8/4P3/4K3 w - - c9 "2-0";`} {
+		if _, err := ParseTuningPosition(line); err == nil {
+			t.Error("expected an error for", line)
+		}
+	}
+}
+
+func TestTuneFindsTheKnight(t *testing.T) {
+	lines := []string{
+		//a knight up wins, the same material draws
+		"rnbqkb1r/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - [1-0]",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKB1R w KQkq - [0-1]",
+		"r1bqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - [1-0]",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/R1BQKBNR b KQkq - [0-1]",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - [0.5]",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - [0.5]",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - [0.5]",
+	}
+
+	var positions []TuningPosition
+	for _, line := range lines {
+		position, err := ParseTuningPosition(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		positions = append(positions, position)
+	}
+
+	params := DefaultEvalParams()
+	params.KnightValue = 50
+	scores := evaluateAll(positions, params, 2)
+	before := tuningError(positions, scores, bestScalingConstant(positions, scores))
+
+	passes := 0
+	tuned, k, after := TuneEvalParams(positions, params, TuneOptions{
+		Threads: 2,
+		Passes:  10,
+		Step:    20,
+		Only:    []string{"KnightValue"},
+		Info:    func(TuneInfo) { passes++ },
+	})
+
+	if tuned.KnightValue <= params.KnightValue {
+		t.Errorf("the knight should be worth more than %d, got %d", params.KnightValue, tuned.KnightValue)
+	}
+	if after >= before || k <= 0 {
+		t.Errorf("the error should go down: %v before, %v after with K %v", before, after, k)
+	}
+	if passes == 0 {
+		t.Error("Info should get called after every pass")
+	}
+
+	tuned.KnightValue = params.KnightValue
+	if tuned != params {
+		t.Error("only the knight should have changed")
+	}
+}
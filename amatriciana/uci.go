@@ -0,0 +1,549 @@
+package amatriciana
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type uciEngine struct {
+	out     io.Writer
+	outLock sync.Mutex
+
+	board   Board
+	multiPV int
+	threads int
+	table   *TranspositionTable
+
+	ownBook     bool
+	book        *Book
+	bookOptions BookOptions
+
+	params  EvalParams
+	network *Network
+	useNNUE bool
+	//nil after the weights change, the next search makes a new one
+	evaluator *Evaluator
+
+	//stop gets closed to interrupt the search, done gets closed by the
+	//search once it has printed its bestmove
+	stop     chan struct{}
+	done     chan struct{}
+	infinite bool
+	//closed on ponderhit, nil when the search isn't pondering
+	ponderHit chan struct{}
+}
+
+//UCI speaks the Universal Chess Interface protocol, reading commands from in
+//and writing the answers to out until it gets "quit" or in runs out
+func UCI(in io.Reader, out io.Writer) error {
+	engine := &uciEngine{
+		out:     out,
+		board:   NewBoard(),
+		multiPV: 1,
+		threads: 1,
+		table:   NewTranspositionTable(defaultHashSize),
+
+		params:    DefaultEvalParams(),
+		evaluator: defaultEvaluator,
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			engine.println("id name amatriciana")
+			engine.println("id author lorenzobotti")
+			engine.println("option name MultiPV type spin default 1 min 1 max 256")
+			engine.println("option name Threads type spin default 1 min 1 max 512")
+			engine.println("option name Hash type spin default", defaultHashSize, "min 1 max 65536")
+			engine.println("option name Ponder type check default false")
+			engine.println("option name OwnBook type check default false")
+			engine.println("option name BookFile type string default <empty>")
+			engine.println("option name BookDepth type spin default 0 min 0 max 1000")
+			engine.println("option name BookSelection type combo default weighted var weighted var best var random")
+			engine.println("option name SyzygyPath type string default <empty>")
+			engine.println("option name EvalFile type string default <empty>")
+			engine.println("option name UseNNUE type check default false")
+			engine.println("option name NNUEFile type string default <empty>")
+			for _, param := range engine.params.List() {
+				engine.println("option name", param.Name, "type spin default", *param.Value, "min -10000 max 10000")
+			}
+			engine.println("uciok")
+		case "isready":
+			engine.println("readyok")
+		case "setoption":
+			engine.setOption(fields[1:])
+		case "ucinewgame":
+			engine.stopSearch()
+			engine.board = NewBoard()
+			engine.table.Clear()
+		case "position":
+			engine.stopSearch()
+			err := engine.position(fields[1:])
+			if err != nil {
+				engine.println("info string", err.Error())
+			}
+		case "go":
+			engine.stopSearch()
+			engine.goSearch(fields[1:])
+		case "ponderhit":
+			engine.ponderHitSearch()
+		case "stop":
+			engine.stopSearch()
+		case "quit":
+			engine.stopSearch()
+			return nil
+		default:
+			engine.println("info string unknown command", fields[0])
+		}
+	}
+
+	//if the input runs out in the middle of a search, let it finish,
+	//unless it's one that would never finish on its own
+	if engine.infinite || engine.ponderHit != nil {
+		engine.stopSearch()
+	}
+	engine.waitSearch()
+	return scanner.Err()
+}
+
+func (e *uciEngine) println(a ...interface{}) {
+	e.outLock.Lock()
+	defer e.outLock.Unlock()
+
+	fmt.Fprintln(e.out, a...)
+}
+
+//setoption name <name> value <value>
+//both the name and the value can have spaces in them
+func (e *uciEngine) setOption(args []string) {
+	valueAt := len(args)
+	for i, arg := range args {
+		if arg == "value" {
+			valueAt = i
+			break
+		}
+	}
+
+	name, value := "", ""
+	if len(args) > 0 && args[0] == "name" {
+		name = strings.Join(args[1:valueAt], " ")
+	}
+	if valueAt < len(args) {
+		value = strings.Join(args[valueAt+1:], " ")
+	}
+
+	switch strings.ToLower(name) {
+	//the gui only tells us so we know it might send "go ponder",
+	//there's nothing to set up for it
+	case "ponder":
+		return
+	case "ownbook":
+		e.ownBook = value == "true"
+		return
+	case "bookfile":
+		e.loadBook(value)
+		return
+	case "bookselection":
+		switch value {
+		case "best":
+			e.bookOptions.Selection = BookBest
+		case "random":
+			e.bookOptions.Selection = BookRandom
+		default:
+			e.bookOptions.Selection = BookWeighted
+		}
+		return
+	case "syzygypath":
+		err := SetSyzygyPath(value)
+		if err != nil {
+			e.println("info string couldn't load the tablebases:", err.Error())
+		} else if SyzygyMaxPieces() > 0 {
+			e.println("info string found tablebases up to", SyzygyMaxPieces(), "pieces")
+		}
+		return
+	case "evalfile":
+		e.loadEvalFile(value)
+		return
+	case "usennue":
+		e.useNNUE = value == "true"
+		e.evaluator = nil
+		return
+	case "nnuefile":
+		e.loadNetwork(value)
+		return
+	}
+
+	if e.setEvalParam(name, value) {
+		return
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		e.println("info string invalid value", value, "for", name)
+		return
+	}
+
+	switch strings.ToLower(name) {
+	case "multipv":
+		e.multiPV = number
+	case "threads":
+		e.threads = number
+	case "hash":
+		e.table = NewTranspositionTable(number)
+	case "bookdepth":
+		e.bookOptions.Depth = number
+	default:
+		e.println("info string unknown option", name)
+	}
+}
+
+func (e *uciEngine) loadBook(path string) {
+	if path == "" || path == "<empty>" {
+		e.book = nil
+		return
+	}
+
+	book, err := LoadBook(path)
+	if err != nil {
+		e.println("info string couldn't load the book:", err.Error())
+		return
+	}
+
+	e.book = book
+}
+
+func (e *uciEngine) loadEvalFile(path string) {
+	if path == "" || path == "<empty>" {
+		e.params = DefaultEvalParams()
+		e.evaluator = nil
+		return
+	}
+
+	params, err := LoadEvalParams(path)
+	if err != nil {
+		e.println("info string couldn't load the evaluation weights:", err.Error())
+		return
+	}
+
+	e.params = params
+	e.evaluator = nil
+}
+
+func (e *uciEngine) loadNetwork(path string) {
+	e.evaluator = nil
+	if path == "" || path == "<empty>" {
+		e.network = nil
+		return
+	}
+
+	network, err := LoadNetwork(path)
+	if err != nil {
+		e.network = nil
+		e.println("info string couldn't load the network:", err.Error())
+		return
+	}
+
+	e.network = network
+}
+
+//the evaluation weights are options too, so they can be tuned from outside.
+//false if name isn't one of them
+func (e *uciEngine) setEvalParam(name, value string) bool {
+	for _, param := range e.params.List() {
+		if !strings.EqualFold(param.Name, name) {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			e.println("info string invalid value", value, "for", name)
+			return true
+		}
+
+		*param.Value = number
+		e.evaluator = nil
+		return true
+	}
+
+	return false
+}
+
+//position [startpos | fen <fen>] [moves <move>...]
+func (e *uciEngine) position(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("position needs startpos or fen")
+	}
+
+	movesAt := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			movesAt = i
+			break
+		}
+	}
+
+	var board Board
+	switch args[0] {
+	case "startpos":
+		board = NewBoard()
+	case "fen":
+		fen, err := BoardFromFEN(strings.Join(args[1:movesAt], " "))
+		if err != nil {
+			return err
+		}
+		board = fen
+	default:
+		return fmt.Errorf("unknown position type %s", args[0])
+	}
+
+	if movesAt < len(args) {
+		for _, m := range args[movesAt+1:] {
+			err := board.PerformMove(m)
+			if err != nil {
+				return fmt.Errorf("couldn't play %s: %s", m, err.Error())
+			}
+		}
+	}
+
+	e.board = board
+	return nil
+}
+
+//go [depth <plies>] [nodes <n>] [movetime <ms>] [wtime <ms>] [btime <ms>] [winc <ms>]
+//[binc <ms>] [movestogo <moves>] [infinite] [ponder]
+func (e *uciEngine) goSearch(args []string) {
+	//the scores in the table came from the old weights
+	if e.evaluator == nil {
+		if e.useNNUE && e.network != nil {
+			e.evaluator = NewNetworkEvaluator(e.network)
+		} else {
+			if e.useNNUE {
+				e.println("info string there's no network, using the classic evaluation")
+			}
+			e.evaluator = NewEvaluator(e.params)
+		}
+		e.table.Clear()
+	}
+
+	opts := SearchOptions{MultiPV: e.multiPV, Threads: e.threads, Table: e.table, Evaluator: e.evaluator}
+	infinite, ponder := false, false
+
+	clock := make(map[color]time.Duration, 2)
+	increment := make(map[color]time.Duration, 2)
+	movesToGo := 0
+
+	for i := 0; i < len(args); i++ {
+		value := 0
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+		millis := time.Duration(value) * time.Millisecond
+
+		switch args[i] {
+		case "depth":
+			opts.Depth = value
+			i++
+		case "nodes":
+			opts.Nodes = value
+			i++
+		case "movetime":
+			opts.MoveTime = millis
+			i++
+		case "wtime":
+			clock[white] = millis
+			i++
+		case "btime":
+			clock[black] = millis
+			i++
+		case "winc":
+			increment[white] = millis
+			i++
+		case "binc":
+			increment[black] = millis
+			i++
+		case "movestogo":
+			movesToGo = value
+			i++
+		case "infinite":
+			infinite = true
+		case "ponder":
+			ponder = true
+		}
+	}
+
+	//a book move gets played right away, but an infinite or pondering
+	//search still has to wait for the gui
+	if e.ownBook && e.book != nil && !infinite && !ponder {
+		bookMove, found := e.book.PickMove(e.board, e.bookOptions)
+		if found {
+			e.println("info string book move")
+			e.println("bestmove", bookMove.UCIString())
+			return
+		}
+	}
+
+	us := e.board.turn
+	if opts.MoveTime == 0 && clock[us] > 0 {
+		opts.MoveTime = timeForMove(clock[us], increment[us], movesToGo)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	e.stop, e.done, e.infinite = stop, done, infinite
+
+	var ponderHit chan struct{}
+	if ponder {
+		ponderHit = make(chan struct{})
+		e.ponderHit = ponderHit
+		opts.PonderHit = ponderHit
+	}
+
+	opts.Stop = stop
+	opts.Info = e.printInfo
+
+	board := e.board
+	table := e.table
+	go func() {
+		defer close(done)
+
+		lines := board.Search(opts)
+
+		//in infinite mode the bestmove has to wait for a stop,
+		//and while pondering it has to wait for the opponent's move
+		if infinite {
+			<-stop
+		} else if ponder {
+			select {
+			case <-stop:
+			case <-ponderHit:
+			}
+		}
+
+		if len(lines) == 0 {
+			e.println("bestmove 0000")
+			return
+		}
+
+		best := lines[0].Move
+		reply, found := board.PonderMove(lines[0], table)
+		if found {
+			e.println("bestmove", best.UCIString(), "ponder", reply.UCIString())
+		} else {
+			e.println("bestmove", best.UCIString())
+		}
+	}()
+}
+
+//PonderMove is the move we expect the opponent to answer line with, the one
+//to ponder on. it's usually the second move of the pv, but when the pv got cut
+//short the table (if there is one) might still know it. if it doesn't it's
+//the reply the move ordering likes the most, so there's always one unless
+//the game is over
+func (b Board) PonderMove(line SearchLine, table *TranspositionTable) (move, bool) {
+	if len(line.PV) > 1 {
+		return line.PV[1], true
+	}
+
+	afterBest := b.Clone()
+	afterBest.move(line.Move)
+
+	replies := afterBest.moves(afterBest.turn)
+	if len(replies) == 0 {
+		return move{}, false
+	}
+
+	if table != nil {
+		if entry, found := table.probe(afterBest.Hash()); found {
+			for _, reply := range replies {
+				if reply.matches(entry.move) {
+					return reply, true
+				}
+			}
+		}
+	}
+
+	//a search that stopped after the first iteration doesn't leave anything
+	//in the table for the position after its move
+	return afterBest.orderMoves(replies)[0], true
+}
+
+//how much of the clock to spend on this move: an even share of what's left
+//for the moves to go (or 30 if we don't know) plus most of the increment
+func timeForMove(remaining, increment time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = 30
+	}
+
+	budget := remaining/time.Duration(movesToGo) + increment*3/4
+
+	//leave a bit on the clock for the time it takes to talk to the gui
+	safetyMargin := 50 * time.Millisecond
+	if budget > remaining-safetyMargin {
+		budget = remaining - safetyMargin
+	}
+	if budget < 10*time.Millisecond {
+		budget = 10 * time.Millisecond
+	}
+
+	return budget
+}
+
+//the opponent played the move we were pondering on: the search keeps
+//going, but now it's on our clock
+func (e *uciEngine) ponderHitSearch() {
+	if e.ponderHit == nil {
+		return
+	}
+
+	close(e.ponderHit)
+	e.ponderHit = nil
+}
+
+func (e *uciEngine) printInfo(info SearchInfo) {
+	millis := info.Time.Milliseconds()
+	nps := int64(info.Nodes)
+	if millis > 0 {
+		nps = int64(info.Nodes) * 1000 / millis
+	}
+
+	for i, line := range info.Lines {
+		pv := make([]string, len(line.PV))
+		for j, m := range line.PV {
+			pv[j] = m.UCIString()
+		}
+
+		e.println(fmt.Sprintf("info depth %d multipv %d score %s nodes %d nps %d time %d pv %s",
+			info.Depth, i+1, line.Score.UCI(), info.Nodes, nps, millis, strings.Join(pv, " ")))
+	}
+}
+
+//tells the running search to stop and waits for its bestmove
+func (e *uciEngine) stopSearch() {
+	if e.stop == nil {
+		return
+	}
+
+	close(e.stop)
+	<-e.done
+	e.stop, e.done, e.ponderHit = nil, nil, nil
+}
+
+func (e *uciEngine) waitSearch() {
+	if e.done == nil {
+		return
+	}
+
+	<-e.done
+	e.stop, e.done, e.ponderHit = nil, nil, nil
+}
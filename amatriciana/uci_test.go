@@ -0,0 +1,227 @@
+package amatriciana
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUCIMultiPV(t *testing.T) {
+	input := strings.Join([]string{
+		"uci",
+		"setoption name MultiPV value 3",
+		"isready",
+		"position startpos moves e2e4 e7e5",
+		"go depth 1",
+	}, "\n")
+
+	var output bytes.Buffer
+	err := UCI(strings.NewReader(input), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"uciok", "readyok", "multipv 1", "multipv 3", "bestmove"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Error("output is missing", expected)
+		}
+	}
+
+	if strings.Contains(output.String(), "multipv 4") {
+		t.Error("there should only be three lines")
+	}
+}
+
+func TestUCIPonder(t *testing.T) {
+	input := strings.Join([]string{
+		"position startpos moves e2e4",
+		"go ponder depth 3",
+		"ponderhit",
+	}, "\n")
+
+	var output bytes.Buffer
+	err := UCI(strings.NewReader(input), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	last := strings.Fields(lines[len(lines)-1])
+	if len(last) != 4 || last[0] != "bestmove" || last[2] != "ponder" {
+		t.Error("expected a bestmove with a ponder move, got", lines[len(lines)-1])
+	}
+}
+
+//a bytes.Buffer the test can read while the engine writes to it
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestUCIPonderHitWithClock(t *testing.T) {
+	in, commands := io.Pipe()
+	var output syncBuffer
+	done := make(chan error)
+	go func() {
+		done <- UCI(in, &output)
+	}()
+
+	io.WriteString(commands, "position startpos moves e2e4\ngo ponder wtime 1000 btime 1000\n")
+
+	//a second on the clock is a few tens of milliseconds for the move,
+	//without the ponderhit it has to keep thinking way past that
+	time.Sleep(300 * time.Millisecond)
+	if strings.Contains(output.String(), "bestmove") {
+		t.Fatal("bestmove came before the ponderhit:", output.String())
+	}
+
+	//after the ponderhit it's a normal timed search and has to finish on its own
+	io.WriteString(commands, "ponderhit\n")
+	commands.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the search didn't stop after the ponderhit")
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	last := strings.Fields(lines[len(lines)-1])
+	if len(last) != 4 || last[0] != "bestmove" || last[2] != "ponder" {
+		t.Error("expected a bestmove with a ponder move, got", lines[len(lines)-1])
+	}
+}
+
+func TestPonderMoveWithShortPV(t *testing.T) {
+	board := NewBoard()
+	e4, err := board.parseMove("e2e4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//an empty table and a pv with only the move, like a search stopped at depth 1
+	line := SearchLine{Move: e4, PV: []move{e4}}
+	for _, table := range []*TranspositionTable{nil, NewTranspositionTable(1)} {
+		reply, found := board.PonderMove(line, table)
+		if !found || reply.color != black {
+			t.Error("there should be a black reply to ponder on, got", reply.UCIString(), found)
+		}
+	}
+
+	//the table's move comes first when it has one
+	afterE4 := board.Clone()
+	afterE4.move(e4)
+	c5, _ := afterE4.parseMove("c7c5")
+	table := NewTranspositionTable(1)
+	table.store(afterE4.Hash(), ttEntry{move: c5.pack(), depth: 1})
+	if reply, found := board.PonderMove(line, table); !found || reply != c5 {
+		t.Error("the ponder move should be the table's c7c5, got", reply.UCIString())
+	}
+}
+
+func TestUCIStopWhilePondering(t *testing.T) {
+	input := strings.Join([]string{
+		"position startpos",
+		"go ponder movetime 10",
+		"stop",
+		"isready",
+	}, "\n")
+
+	var output bytes.Buffer
+	err := UCI(strings.NewReader(input), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//stop has to answer with a bestmove before anything else gets done
+	bestmove := strings.Index(output.String(), "bestmove")
+	readyok := strings.Index(output.String(), "readyok")
+	if bestmove < 0 || readyok < bestmove {
+		t.Error("stop didn't produce a bestmove in time:", output.String())
+	}
+}
+
+func TestTimeForMove(t *testing.T) {
+	if timeForMove(30*time.Second, 0, 0) != time.Second {
+		t.Error("thirty seconds with no moves to go should give one second")
+	}
+	if timeForMove(100*time.Millisecond, 5*time.Second, 1) != 50*time.Millisecond {
+		t.Error("the increment shouldn't make us lose on time")
+	}
+}
+
+func TestUCIEvalParams(t *testing.T) {
+	input := strings.Join([]string{
+		"uci",
+		"setoption name QueenValue value 100",
+		"setoption name DoubledPawnMg value -20",
+		"position fen 4k3/8/8/8/8/8/3Q4/4K3 w - - 0 1",
+		"go depth 1",
+	}, "\n")
+
+	var output bytes.Buffer
+	err := UCI(strings.NewReader(input), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"option name QueenValue type spin default 900", "option name EvalFile", "bestmove"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Error("output is missing", expected)
+		}
+	}
+	if strings.Contains(output.String(), "invalid value") || strings.Contains(output.String(), "unknown option") {
+		t.Error("the weights should be accepted, got", output.String())
+	}
+}
+
+func TestUCINetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "net.nnue")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomNetwork(8, 5).Write(file)
+	file.Close()
+
+	input := strings.Join([]string{
+		"setoption name UseNNUE value true",
+		"setoption name NNUEFile value " + path,
+		"position startpos",
+		"go depth 1",
+	}, "\n")
+
+	var output bytes.Buffer
+	if err := UCI(strings.NewReader(input), &output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "bestmove") || strings.Contains(output.String(), "info string") {
+		t.Error("expected a search with the network, got", output.String())
+	}
+}
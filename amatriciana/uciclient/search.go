@@ -0,0 +1,267 @@
+package uciclient
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Limits say when a search should stop, the zero values aren't sent
+type Limits struct {
+	Depth     int
+	Nodes     int
+	MoveTime  time.Duration
+	WTime     time.Duration
+	BTime     time.Duration
+	WInc      time.Duration
+	BInc      time.Duration
+	MovesToGo int
+	//Infinite searches until Stop or until the context is done
+	Infinite bool
+	//SearchMoves are the only root moves to look at
+	SearchMoves []string
+}
+
+//the go command for the limits
+func (l Limits) command() string {
+	parts := []string{"go"}
+	add := func(name string, value int64) {
+		if value > 0 {
+			parts = append(parts, name, strconv.FormatInt(value, 10))
+		}
+	}
+
+	add("depth", int64(l.Depth))
+	add("nodes", int64(l.Nodes))
+	add("movetime", l.MoveTime.Milliseconds())
+	add("wtime", l.WTime.Milliseconds())
+	add("btime", l.BTime.Milliseconds())
+	add("winc", l.WInc.Milliseconds())
+	add("binc", l.BInc.Milliseconds())
+	add("movestogo", int64(l.MovesToGo))
+	if l.Infinite {
+		parts = append(parts, "infinite")
+	}
+	if len(l.SearchMoves) > 0 {
+		parts = append(parts, "searchmoves")
+		parts = append(parts, l.SearchMoves...)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+//Score is a score like the engine sent it, from the side to move's point of view
+type Score struct {
+	//Centipawns is the score when it isn't a mate
+	Centipawns int
+	//Mate is how many moves away a mate is, negative when the side to move
+	//gets mated. 0 if it isn't a mate
+	Mate int
+	//the score is only a bound, the search didn't finish with it
+	Lowerbound bool
+	Upperbound bool
+}
+
+//String writes the score back like uci, cp 125 or mate -2
+func (s Score) String() string {
+	if s.Mate != 0 {
+		return "mate " + strconv.Itoa(s.Mate)
+	}
+	return "cp " + strconv.Itoa(s.Centipawns)
+}
+
+//Info is an info line, the fields the engine didn't send are zero
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int
+	Score    *Score
+	Nodes    int64
+	NPS      int64
+	Time     time.Duration
+	HashFull int
+	TBHits   int64
+	CurrMove string
+	PV       []string
+	//String is the free text after info string
+	String string
+}
+
+//ParseInfo reads an info line, the fields it doesn't know get skipped
+func ParseInfo(line string) (Info, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, errors.New("not an info line")
+	}
+
+	var info Info
+	number := func(i int) int64 {
+		if i >= len(fields) {
+			return 0
+		}
+		n, _ := strconv.ParseInt(fields[i], 10, 64)
+		return n
+	}
+
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			info.Depth = int(number(i + 1))
+			i++
+		case "seldepth":
+			info.SelDepth = int(number(i + 1))
+			i++
+		case "multipv":
+			info.MultiPV = int(number(i + 1))
+			i++
+		case "nodes":
+			info.Nodes = number(i + 1)
+			i++
+		case "nps":
+			info.NPS = number(i + 1)
+			i++
+		case "time":
+			info.Time = time.Duration(number(i+1)) * time.Millisecond
+			i++
+		case "hashfull":
+			info.HashFull = int(number(i + 1))
+			i++
+		case "tbhits":
+			info.TBHits = number(i + 1)
+			i++
+		case "currmove":
+			if i+1 < len(fields) {
+				info.CurrMove = fields[i+1]
+			}
+			i++
+		case "score":
+			score := &Score{}
+			if i+2 < len(fields) {
+				switch fields[i+1] {
+				case "cp":
+					score.Centipawns = int(number(i + 2))
+				case "mate":
+					score.Mate = int(number(i + 2))
+				}
+				i += 2
+			}
+			for i+1 < len(fields) && (fields[i+1] == "lowerbound" || fields[i+1] == "upperbound") {
+				score.Lowerbound = score.Lowerbound || fields[i+1] == "lowerbound"
+				score.Upperbound = score.Upperbound || fields[i+1] == "upperbound"
+				i++
+			}
+			info.Score = score
+		case "pv":
+			//the pv goes on until the end of the line
+			info.PV = append([]string{}, fields[i+1:]...)
+			i = len(fields)
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			i = len(fields)
+		}
+	}
+
+	return info, nil
+}
+
+//BestMove is how a search ended
+type BestMove struct {
+	Move   string
+	Ponder string
+	//Info is the last info with a score for the first line, so it's there
+	//even if nobody read the Info channel
+	Info Info
+}
+
+//Search is a search going on in the engine
+type Search struct {
+	//Info gets the engine's info lines as they come. if nobody reads them
+	//fast enough the ones that don't fit get dropped
+	Info <-chan Info
+
+	engine   *Engine
+	stopOnce sync.Once
+	done     chan struct{}
+	best     BestMove
+	err      error
+}
+
+//Go starts a search of the position that was set last. when ctx is done
+//the search gets stopped, and what the engine answers is still the result
+func (e *Engine) Go(ctx context.Context, limits Limits) (*Search, error) {
+	if err := e.send(limits.command()); err != nil {
+		return nil, err
+	}
+
+	info := make(chan Info, 256)
+	s := &Search{Info: info, engine: e, done: make(chan struct{})}
+
+	go func() {
+		defer close(s.done)
+		defer close(info)
+
+		var grace <-chan time.Time
+		cancelled := ctx.Done()
+		for {
+			select {
+			case line, ok := <-e.lines:
+				if !ok {
+					s.err = ErrEngineQuit
+					return
+				}
+
+				fields := strings.Fields(line)
+				if len(fields) == 0 {
+					continue
+				}
+				switch fields[0] {
+				case "info":
+					parsed, err := ParseInfo(line)
+					if err != nil {
+						continue
+					}
+					if parsed.Score != nil && parsed.MultiPV <= 1 {
+						s.best.Info = parsed
+					}
+					select {
+					case info <- parsed:
+					default:
+					}
+				case "bestmove":
+					if len(fields) > 1 {
+						s.best.Move = fields[1]
+					}
+					if len(fields) > 3 && fields[2] == "ponder" {
+						s.best.Ponder = fields[3]
+					}
+					return
+				}
+			case <-cancelled:
+				cancelled = nil
+				s.Stop()
+				grace = time.After(stopGrace)
+			case <-grace:
+				s.err = errors.New("the engine didn't stop: " + ctx.Err().Error())
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+//Stop tells the engine to stop searching, the best move comes from Wait
+func (s *Search) Stop() {
+	s.stopOnce.Do(func() {
+		s.engine.send("stop")
+	})
+}
+
+//Wait waits for the best move
+func (s *Search) Wait() (BestMove, error) {
+	<-s.done
+	return s.best, s.err
+}
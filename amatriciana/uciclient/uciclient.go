@@ -0,0 +1,254 @@
+//Package uciclient runs chess engines that speak the Universal Chess Interface
+//in other processes and talks to them: the handshake, the options, the
+//positions and the searches, with what the engine says parsed into structs.
+//
+//an Engine does one thing at a time, only Search.Stop can be called while
+//something else is going on
+package uciclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//how long an engine gets to answer stop or quit before it's given up on
+const stopGrace = 2 * time.Second
+
+//ErrEngineQuit is what you get when the engine's output ends while
+//something is waiting for it
+var ErrEngineQuit = errors.New("the engine quit")
+
+//Engine is an engine running in another process
+type Engine struct {
+	//Name and Author are what the engine said in the handshake
+	Name    string
+	Author  string
+	Options []Option
+
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	inLock sync.Mutex
+	lines  chan string
+}
+
+//Option is an option the engine said it has
+type Option struct {
+	Name    string
+	Type    string
+	Default string
+	Min     int
+	Max     int
+	//Vars are the choices of a combo
+	Vars []string
+}
+
+//Start launches the engine at path with args and does the handshake,
+//ctx is how long it can take
+func Start(ctx context.Context, path string, args ...string) (*Engine, error) {
+	cmd := exec.Command(path, args...)
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{cmd: cmd, in: in, lines: make(chan string, 64)}
+	go func() {
+		defer close(e.lines)
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			e.lines <- scanner.Text()
+		}
+	}()
+
+	if err := e.handshake(ctx); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) handshake(ctx context.Context) error {
+	if err := e.send("uci"); err != nil {
+		return err
+	}
+
+	return e.readUntil(ctx, "uciok", func(line string) {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "name":
+			e.Name = strings.Join(fields[2:], " ")
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "author":
+			e.Author = strings.Join(fields[2:], " ")
+		case len(fields) > 0 && fields[0] == "option":
+			e.Options = append(e.Options, parseOption(fields[1:]))
+		}
+	})
+}
+
+//option name <name> type <type> [default <x>] [min <x>] [max <x>] [var <x>]...
+//where everything but the keywords can have spaces
+func parseOption(fields []string) Option {
+	var option Option
+	key := ""
+	var value []string
+
+	flush := func() {
+		text := strings.Join(value, " ")
+		switch key {
+		case "name":
+			option.Name = text
+		case "type":
+			option.Type = text
+		case "default":
+			option.Default = text
+		case "min":
+			option.Min, _ = strconv.Atoi(text)
+		case "max":
+			option.Max, _ = strconv.Atoi(text)
+		case "var":
+			option.Vars = append(option.Vars, text)
+		}
+		value = nil
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "name", "type", "default", "min", "max", "var":
+			flush()
+			key = field
+		default:
+			value = append(value, field)
+		}
+	}
+	flush()
+
+	return option
+}
+
+//readUntil gives every line to handle until one starts with prefix
+func (e *Engine) readUntil(ctx context.Context, prefix string, handle func(string)) error {
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrEngineQuit
+			}
+			if strings.HasPrefix(line, prefix) {
+				return nil
+			}
+			if handle != nil {
+				handle(line)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %v", prefix, ctx.Err())
+		}
+	}
+}
+
+func (e *Engine) send(command string) error {
+	e.inLock.Lock()
+	defer e.inLock.Unlock()
+
+	_, err := io.WriteString(e.in, command+"\n")
+	return err
+}
+
+//Option finds an option by name, without caring about case like the protocol
+func (e *Engine) Option(name string) (Option, bool) {
+	for _, option := range e.Options {
+		if strings.EqualFold(option.Name, name) {
+			return option, true
+		}
+	}
+	return Option{}, false
+}
+
+//SetOption sets one of the engine's options, buttons don't need a value
+func (e *Engine) SetOption(name, value string) error {
+	option, found := e.Option(name)
+	if !found {
+		return fmt.Errorf("the engine doesn't have a %s option", name)
+	}
+
+	if option.Type == "button" {
+		return e.send("setoption name " + option.Name)
+	}
+	return e.send("setoption name " + option.Name + " value " + value)
+}
+
+//IsReady waits until the engine is done with everything it was told
+func (e *Engine) IsReady(ctx context.Context) error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	return e.readUntil(ctx, "readyok", nil)
+}
+
+//NewGame tells the engine that the next position is from another game
+//and waits for it to be ready
+func (e *Engine) NewGame(ctx context.Context) error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.IsReady(ctx)
+}
+
+//Position sets the position the engine will search: the uci moves played
+//from fen, or from the usual starting position if fen is empty or startpos
+func (e *Engine) Position(fen string, moves []string) error {
+	command := "position startpos"
+	if fen != "" && fen != "startpos" {
+		command = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		command += " moves " + strings.Join(moves, " ")
+	}
+	return e.send(command)
+}
+
+//FENer is a position that can write itself as a fen, like amatriciana.Board
+type FENer interface {
+	FEN() string
+}
+
+//PositionFrom is Position with a board instead of its fen
+func (e *Engine) PositionFrom(start FENer, moves []string) error {
+	return e.Position(start.FEN(), moves)
+}
+
+//Close asks the engine to quit, and kills it if it takes too long
+func (e *Engine) Close() error {
+	e.send("quit")
+	e.in.Close()
+
+	exited := make(chan error, 1)
+	go func() {
+		//whatever is left has to be read for the process to finish
+		for range e.lines {
+		}
+		exited <- e.cmd.Wait()
+	}()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(stopGrace):
+		e.cmd.Process.Kill()
+		return <-exited
+	}
+}
@@ -0,0 +1,189 @@
+package uciclient
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//the tests talk to gochess, built from the repository. it lives in gopath
+//mode next to the module, so it gets built without modules
+var enginePath string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "uciclient")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	path := filepath.Join(dir, "gochess")
+	build := exec.Command("go", "build", "-o", path, ".")
+	build.Dir = filepath.Join("..", "..", "gochess")
+	build.Env = append(os.Environ(), "GO111MODULE=off", "GOFLAGS=")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Printf("couldn't build gochess, skipping the tests that need it: %v\n%s", err, out)
+	} else {
+		enginePath = path
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startEngine(t *testing.T) *Engine {
+	if enginePath == "" {
+		t.Skip("there's no engine to test with")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	engine, err := Start(ctx, enginePath, "uci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestHandshake(t *testing.T) {
+	engine := startEngine(t)
+	defer engine.Close()
+
+	if engine.Name != "amatriciana" || engine.Author == "" {
+		t.Errorf("expected amatriciana and an author, got %q and %q", engine.Name, engine.Author)
+	}
+
+	hash, found := engine.Option("hash")
+	if !found || hash.Type != "spin" || hash.Default != "16" || hash.Min != 1 || hash.Max != 65536 {
+		t.Errorf("unexpected hash option %+v", hash)
+	}
+	selection, found := engine.Option("BookSelection")
+	if !found || len(selection.Vars) != 3 || selection.Default != "weighted" {
+		t.Errorf("unexpected combo option %+v", selection)
+	}
+
+	if err := engine.SetOption("Threads", "2"); err != nil {
+		t.Error(err)
+	}
+	if err := engine.SetOption("Contempt", "10"); err == nil {
+		t.Error("expected an error for an option the engine doesn't have")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := engine.NewGame(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+type fen string
+
+func (f fen) FEN() string { return string(f) }
+
+func TestSearch(t *testing.T) {
+	engine := startEngine(t)
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := engine.PositionFrom(fen("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1"), nil); err != nil {
+		t.Fatal(err)
+	}
+	search, err := engine.Go(ctx, Limits{Depth: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos := 0
+	for info := range search.Info {
+		if info.Depth == 0 || len(info.PV) == 0 {
+			t.Errorf("unexpected info %+v", info)
+		}
+		infos++
+	}
+
+	best, err := search.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos == 0 {
+		t.Error("no info came through")
+	}
+	if best.Move != "a1a8" || best.Info.Score == nil || best.Info.Score.Mate != 1 {
+		t.Errorf("expected a1a8 with mate 1, got %s with %+v", best.Move, best.Info)
+	}
+
+	if err := engine.Position("", []string{"e2e4", "e7e5"}); err != nil {
+		t.Fatal(err)
+	}
+	search, err = engine.Go(ctx, Limits{Nodes: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	best, err = search.Wait()
+	if err != nil || best.Move == "" {
+		t.Errorf("expected a move, got %q and %v", best.Move, err)
+	}
+}
+
+func TestContextStopsTheSearch(t *testing.T) {
+	engine := startEngine(t)
+	defer engine.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	engine.Position("startpos", nil)
+	started := time.Now()
+	search, err := engine.Go(ctx, Limits{Infinite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	best, err := search.Wait()
+	if err != nil || best.Move == "" {
+		t.Errorf("expected a move after the stop, got %q and %v", best.Move, err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("the search took %v to stop", elapsed)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info, err := ParseInfo("info depth 12 seldepth 18 multipv 2 score cp -35 upperbound nodes 123456 nps 98000 time 1260 hashfull 41 pv e7e5 g1f3 b8c6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Depth != 12 || info.SelDepth != 18 || info.MultiPV != 2 || info.Nodes != 123456 ||
+		info.NPS != 98000 || info.Time != 1260*time.Millisecond || info.HashFull != 41 || len(info.PV) != 3 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if info.Score == nil || info.Score.Centipawns != -35 || !info.Score.Upperbound || info.Score.String() != "cp -35" {
+		t.Errorf("unexpected score %+v", info.Score)
+	}
+
+	info, _ = ParseInfo("info string book move found")
+	if info.String != "book move found" {
+		t.Errorf("expected the string, got %q", info.String)
+	}
+
+	if _, err := ParseInfo("bestmove e2e4"); err == nil {
+		t.Error("expected an error for a bestmove line")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{Depth: 8, WTime: 60 * time.Second, BTime: 59500 * time.Millisecond, WInc: time.Second, BInc: time.Second}
+	expected := "go depth 8 wtime 60000 btime 59500 winc 1000 binc 1000"
+	if command := limits.command(); command != expected {
+		t.Errorf("expected %q, got %q", expected, command)
+	}
+}
@@ -0,0 +1,91 @@
+package amatriciana
+
+//random numbers to build position hashes out of.
+//they come from a fixed seed so hashes are the same on every run
+var (
+	zobristPieces    [2][6][64]uint64
+	zobristCastling  [4]uint64
+	zobristEnPassant [8]uint64
+	zobristBlack     uint64
+)
+
+func init() {
+	var seed uint64 = 0x9e3779b97f4a7c15
+	next := func() uint64 {
+		//xorshift64*
+		seed ^= seed >> 12
+		seed ^= seed << 25
+		seed ^= seed >> 27
+		return seed * 0x2545f4914f6cdd1d
+	}
+
+	for col := range zobristPieces {
+		for pt := range zobristPieces[col] {
+			for square := range zobristPieces[col][pt] {
+				zobristPieces[col][pt][square] = next()
+			}
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	zobristBlack = next()
+}
+
+//from 0 (a1) to 63 (h8)
+func (a xy) index() int {
+	return (a.y-1)*8 + (a.x - 1)
+}
+
+func (c color) index() int {
+	if c == white {
+		return 0
+	}
+	return 1
+}
+
+//Hash gives back a number that identifies the position.
+//different positions can have the same hash, but it's very unlikely
+func (b Board) Hash() uint64 {
+	var hash uint64
+
+	for _, p := range b.pieces {
+		if !isInBounds(p.position) {
+			continue
+		}
+		hash ^= zobristPieces[p.color.index()][p.pieceType][p.position.index()]
+	}
+
+	rights := [...]bool{b.whiteCanCastle[0], b.whiteCanCastle[1], b.blackCanCastle[0], b.blackCanCastle[1]}
+	for i, canCastle := range rights {
+		if canCastle {
+			hash ^= zobristCastling[i]
+		}
+	}
+
+	if (b.enPassant != xy{0, 0}) {
+		hash ^= zobristEnPassant[b.enPassant.x-1]
+	}
+
+	if b.turn == black {
+		hash ^= zobristBlack
+	}
+
+	return hash
+}
+
+//pawnHash only looks at the pawns, for the pawn structure table
+func (b Board) pawnHash() uint64 {
+	var hash uint64
+
+	for _, p := range b.pieces {
+		if p.pieceType == pawn {
+			hash ^= zobristPieces[p.color.index()][pawn][p.position.index()]
+		}
+	}
+
+	return hash
+}
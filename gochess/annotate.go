@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"../amatriciana"
+)
+
+//gochess annotate [-depth <n>] [-movetime <ms>] [-threads <n>] [-hash <mb>] [-params <file>] [-nnue <file>]
+//[-inaccuracy <cp>] [-mistake <cp>] [-blunder <cp>] [-out <file>] <game.pgn>
+func annotate(args []string) {
+	defaults := amatriciana.DefaultAnnotateOptions()
+
+	flags := flag.NewFlagSet("annotate", flag.ExitOnError)
+	depth := flags.Int("depth", 0, "how deep every position gets searched, 0 means no limit")
+	moveTime := flags.Int("movetime", int(defaults.Search.MoveTime.Milliseconds()), "how long every position gets searched in milliseconds, 0 means no limit")
+	threads := flags.Int("threads", 1, "how many goroutines search")
+	hash := flags.Int("hash", 16, "the transposition table in megabytes")
+	paramsFile := flags.String("params", "", "a json or toml file with the evaluation weights")
+	networkFile := flags.String("nnue", "", "a network file to evaluate with instead of the weights")
+	inaccuracy := flags.Int("inaccuracy", defaults.Inaccuracy, "the centipawns a move has to lose to get a ?!, 0 turns them off")
+	mistake := flags.Int("mistake", defaults.Mistake, "the centipawns a move has to lose to get a ?, 0 turns them off")
+	blunder := flags.Int("blunder", defaults.Blunder, "the centipawns a move has to lose to get a ??, 0 turns them off")
+	out := flags.String("out", "annotated.pgn", "where the annotated games go")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || (*depth <= 0 && *moveTime <= 0) {
+		fmt.Println("usage: gochess annotate [-depth <n>] [-movetime <ms>] [-threads <n>] [-hash <mb>] [-params <file>] [-nnue <file>]")
+		fmt.Println("                        [-inaccuracy <cp>] [-mistake <cp>] [-blunder <cp>] [-out <file>] <game.pgn>")
+		os.Exit(1)
+	}
+
+	evaluator, err := loadEvaluator(*paramsFile, *networkFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	in, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	games, err := amatriciana.ReadPGN(in)
+	in.Close()
+	if err != nil {
+		fmt.Println("couldn't read the games:", err.Error())
+		os.Exit(1)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	defer writer.Flush()
+
+	table := amatriciana.NewTranspositionTable(*hash)
+	opts := amatriciana.AnnotateOptions{
+		Search: amatriciana.SearchOptions{
+			Depth:     *depth,
+			MoveTime:  time.Duration(*moveTime) * time.Millisecond,
+			Threads:   *threads,
+			Table:     table,
+			Evaluator: evaluator,
+		},
+		Inaccuracy: *inaccuracy,
+		Mistake:    *mistake,
+		Blunder:    *blunder,
+		Progress: func(searched, positions int) {
+			fmt.Printf("\r%d/%d positions", searched, positions)
+		},
+	}
+
+	for i, game := range games {
+		fmt.Printf("game %d: %s - %s %s\n", i+1, game.Tags["White"], game.Tags["Black"], game.Result)
+
+		table.Clear()
+		review := game.Annotate(opts)
+		fmt.Println()
+
+		review.Game.Tags["Annotator"] = "gochess"
+		if err := review.Game.WritePGN(writer); err != nil {
+			fmt.Println("couldn't write the game:", err.Error())
+			os.Exit(1)
+		}
+
+		for _, player := range []struct {
+			name   string
+			review amatriciana.PlayerReview
+		}{{"white", review.White}, {"black", review.Black}} {
+			fmt.Printf("%s: %.1f%% accuracy, %.0f average centipawn loss, %d inaccuracies, %d mistakes, %d blunders\n",
+				player.name, player.review.Accuracy, player.review.AverageCentipawnLoss,
+				player.review.Inaccuracies, player.review.Mistakes, player.review.Blunders)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("annotated games written to", *out)
+}
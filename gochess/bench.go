@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"../amatriciana"
+)
+
+//gochess bench [-depth <n>] [-params <file>] [-nnue <file>]
+func bench(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	depth := flags.Int("depth", 4, "how deep every position gets searched")
+	paramsFile := flags.String("params", "", "a json or toml file with the evaluation weights")
+	networkFile := flags.String("nnue", "", "a network file to evaluate with instead of the weights")
+	flags.Parse(args)
+
+	if *depth < 1 || flags.NArg() != 0 {
+		fmt.Println("usage: gochess bench [-depth <n>] [-params <file>] [-nnue <file>]")
+		os.Exit(1)
+	}
+
+	evaluator, err := loadEvaluator(*paramsFile, *networkFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	position := 0
+	result := amatriciana.Bench(*depth, evaluator, func(fen string, nodes int, elapsed time.Duration) {
+		position++
+		fmt.Printf("position %2d: %9d nodes %7dms  %s\n", position, nodes, elapsed.Milliseconds(), fen)
+	})
+
+	fmt.Println()
+	fmt.Println("depth:", *depth)
+	fmt.Println("time:", result.Time.Milliseconds(), "ms")
+	fmt.Println("nodes:", result.Nodes)
+	fmt.Println("nps:", result.NPS())
+}
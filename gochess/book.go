@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"../amatriciana"
+)
+
+//gochess book build [flags] <pgn>...
+//gochess book show <book> [fen]
+func book(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: gochess book build [flags] <pgn>... | gochess book show <book> [fen]")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "build":
+		buildBook(args[1:])
+	case "show":
+		showBook(args[1:])
+	default:
+		fmt.Println("unknown book command", args[0])
+		os.Exit(1)
+	}
+}
+
+func buildBook(args []string) {
+	flags := flag.NewFlagSet("book build", flag.ExitOnError)
+	output := flags.String("o", "book.bin", "where to write the book")
+	minGames := flags.Int("min-games", 1, "how many times a move has to be played to make it in")
+	minScore := flags.Float64("min-score", 0, "the score a move has to have made, from 0 to 1, draws count half")
+	maxPly := flags.Int("max-ply", 30, "how many plies of every game go in the book, 0 for all of them")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println("usage: gochess book build [flags] <pgn>...")
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	games := make([]amatriciana.Game, 0)
+	for _, path := range flags.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Println("couldn't open the pgn:", err.Error())
+			os.Exit(1)
+		}
+
+		fileGames, err := amatriciana.ReadPGN(file)
+		file.Close()
+		if err != nil {
+			fmt.Println(path+":", err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(path+":", len(fileGames), "games")
+		games = append(games, fileGames...)
+	}
+
+	book := amatriciana.BuildBook(games, amatriciana.BookBuildOptions{
+		MaxPly:   *maxPly,
+		MinGames: *minGames,
+		MinScore: *minScore,
+	})
+
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Println("couldn't create the book:", err.Error())
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	err = book.Write(file)
+	if err != nil {
+		fmt.Println("couldn't write the book:", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("wrote", book.Len(), "entries to", *output)
+}
+
+func showBook(args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: gochess book show <book> [fen]")
+		os.Exit(1)
+	}
+
+	book, err := amatriciana.LoadBook(args[0])
+	if err != nil {
+		fmt.Println("couldn't load the book:", err.Error())
+		os.Exit(1)
+	}
+
+	board := amatriciana.NewBoard()
+	if len(args) > 1 {
+		board, err = amatriciana.BoardFromFEN(strings.Join(args[1:], " "))
+		if err != nil {
+			fmt.Println("couldn't parse the fen:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	moves := book.Moves(board)
+	if len(moves) == 0 {
+		fmt.Println("the book doesn't know this position")
+		return
+	}
+
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+
+	for _, m := range moves {
+		share := 0.0
+		if total > 0 {
+			share = float64(m.Weight) * 100 / float64(total)
+		}
+		fmt.Printf("%-6s weight %5d  %5.1f%%\n", m.Move.UCIString(), m.Weight, share)
+	}
+}
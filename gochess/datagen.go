@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"time"
+
+	"../amatriciana"
+)
+
+//what a game gave back, with its number
+type datagenGame struct {
+	number    int
+	positions []amatriciana.TrainingPosition
+	result    string
+}
+
+//gochess datagen [-games <n>] [-nodes <n>] [-random-plies <n>] [-max-plies <n>] [-threads <n>] [-hash <mb>] [-seed <n>] [-params <file>] [-nnue <file>] [-bin <file>] [-text <file>]
+func datagen(args []string) {
+	flags := flag.NewFlagSet("datagen", flag.ExitOnError)
+	games := flags.Int("games", 100, "how many games to play")
+	nodes := flags.Int("nodes", 5000, "how many nodes every move gets")
+	randomPlies := flags.Int("random-plies", 8, "how many random moves every game starts with")
+	maxPlies := flags.Int("max-plies", 400, "games this long are draws")
+	threads := flags.Int("threads", runtime.NumCPU(), "how many games get played at the same time")
+	hash := flags.Int("hash", 16, "the transposition table of every game, in megabytes")
+	seed := flags.Int64("seed", 0, "the seed for the openings, 0 means the time")
+	paramsFile := flags.String("params", "", "a json or toml file with the evaluation weights")
+	networkFile := flags.String("nnue", "", "a network file to evaluate with instead of the weights")
+	binFile := flags.String("bin", "data.bin", "where the positions go in the binary format, nothing if empty")
+	textFile := flags.String("text", "data.txt", "where the positions go as plain text, nothing if empty")
+	flags.Parse(args)
+
+	if *games < 1 || *threads < 1 || flags.NArg() != 0 {
+		fmt.Println("usage: gochess datagen [-games <n>] [-nodes <n>] [-random-plies <n>] [-max-plies <n>] [-threads <n>] [-hash <mb>] [-seed <n>] [-params <file>] [-nnue <file>] [-bin <file>] [-text <file>]")
+		os.Exit(1)
+	}
+
+	evaluator, err := loadEvaluator(*paramsFile, *networkFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	var bin, text *bufio.Writer
+	for _, out := range []struct {
+		path   string
+		writer **bufio.Writer
+	}{{*binFile, &bin}, {*textFile, &text}} {
+		if out.path == "" {
+			continue
+		}
+		file, err := os.Create(out.path)
+		if err != nil {
+			fmt.Println("couldn't create the output:", err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		*out.writer = bufio.NewWriter(file)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	//every goroutine plays games with its own table and its own random
+	//numbers until there are none left, this one writes what they find
+	numbers := make(chan int)
+	go func() {
+		for i := 1; i <= *games; i++ {
+			numbers <- i
+		}
+		close(numbers)
+	}()
+
+	finished := make(chan datagenGame)
+	for id := 0; id < *threads; id++ {
+		go func(id int) {
+			opts := amatriciana.DatagenOptions{
+				Nodes:       *nodes,
+				RandomPlies: *randomPlies,
+				MaxPlies:    *maxPlies,
+				Evaluator:   evaluator,
+				Table:       amatriciana.NewTranspositionTable(*hash),
+				Random:      rand.New(rand.NewSource(*seed + int64(id))),
+			}
+			for number := range numbers {
+				positions, result := amatriciana.PlayTrainingGame(opts)
+				finished <- datagenGame{number, positions, result}
+			}
+		}(id)
+	}
+
+	start := time.Now()
+	total := 0
+	for i := 1; i <= *games; i++ {
+		game := <-finished
+		for _, position := range game.positions {
+			if bin != nil {
+				data, err := position.MarshalBinary()
+				if err != nil {
+					fmt.Println("couldn't pack", position.Board.FEN(), err.Error())
+					continue
+				}
+				bin.Write(data)
+			}
+			if text != nil {
+				fmt.Fprintln(text, position.String())
+			}
+		}
+
+		total += len(game.positions)
+		fmt.Printf("game %d (%d/%d): %s, %d positions, %d in total, %.1f positions/s\n",
+			game.number, i, *games, game.result, len(game.positions), total, float64(total)/time.Since(start).Seconds())
+	}
+
+	for _, writer := range []*bufio.Writer{bin, text} {
+		if writer == nil {
+			continue
+		}
+		if err := writer.Flush(); err != nil {
+			fmt.Println("couldn't write the positions:", err.Error())
+			os.Exit(1)
+		}
+	}
+}
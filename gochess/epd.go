@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"../amatriciana"
+)
+
+//the points of every theme of a suite, sts has 15 of them
+type themeScore struct {
+	positions int
+	solved    int
+	points    int
+	maxPoints int
+}
+
+//gochess epd [-depth <n>] [-movetime <ms>] [-threads <n>] [-hash <mb>] [-params <file>] [-nnue <file>] [-failed] <suite.epd>
+func epd(args []string) {
+	flags := flag.NewFlagSet("epd", flag.ExitOnError)
+	depth := flags.Int("depth", 0, "how deep every position gets searched, 0 means no limit")
+	moveTime := flags.Int("movetime", 1000, "how long every position gets searched in milliseconds, 0 means no limit")
+	threads := flags.Int("threads", 1, "how many goroutines search")
+	hash := flags.Int("hash", 16, "the transposition table in megabytes")
+	paramsFile := flags.String("params", "", "a json or toml file with the evaluation weights")
+	networkFile := flags.String("nnue", "", "a network file to evaluate with instead of the weights")
+	onlyFailed := flags.Bool("failed", false, "only print the positions that weren't solved")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 || (*depth <= 0 && *moveTime <= 0) {
+		fmt.Println("usage: gochess epd [-depth <n>] [-movetime <ms>] [-threads <n>] [-hash <mb>] [-params <file>] [-nnue <file>] [-failed] <suite.epd>")
+		os.Exit(1)
+	}
+
+	evaluator, err := loadEvaluator(*paramsFile, *networkFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	suite, err := readSuite(flags.Arg(0))
+	if err != nil {
+		fmt.Println("couldn't read the suite:", err.Error())
+		os.Exit(1)
+	}
+
+	table := amatriciana.NewTranspositionTable(*hash)
+	opts := amatriciana.SearchOptions{
+		Depth:     *depth,
+		MoveTime:  time.Duration(*moveTime) * time.Millisecond,
+		Threads:   *threads,
+		Table:     table,
+		Evaluator: evaluator,
+	}
+
+	themes := make(map[string]*themeScore)
+	var total themeScore
+	for i, position := range suite {
+		id := position.ID()
+		if id == "" {
+			id = fmt.Sprintf("#%d", i+1)
+		}
+
+		table.Clear()
+		result, err := position.Solve(opts)
+		if err != nil {
+			fmt.Printf("%s: skipped, %s\n", id, err.Error())
+			continue
+		}
+
+		//sts ids look like "STS(v1.0) Undermine.001", the theme is what comes before the number
+		theme := id
+		if dot := strings.LastIndexByte(id, '.'); dot > 0 {
+			theme = id[:dot]
+		}
+		if themes[theme] == nil {
+			themes[theme] = &themeScore{}
+		}
+		for _, score := range []*themeScore{themes[theme], &total} {
+			score.positions++
+			score.points += result.Points
+			score.maxPoints += result.MaxPoints
+			if result.Solved {
+				score.solved++
+			}
+		}
+
+		if *onlyFailed && result.Solved {
+			continue
+		}
+		verdict := "failed"
+		if result.Solved {
+			verdict = "solved"
+		}
+		expected := ""
+		for _, opcode := range []string{"bm", "am"} {
+			if moves, found := position.Operation(opcode); found {
+				expected += fmt.Sprintf(", %s %s", opcode, strings.Join(moves, " "))
+			}
+		}
+		points := ""
+		if result.MaxPoints > 0 {
+			points = fmt.Sprintf(", %d/%d points", result.Points, result.MaxPoints)
+		}
+		fmt.Printf("%s: %s, played %s (%s, depth %d)%s%s\n", id, verdict, result.Move, result.Score, result.Depth, expected, points)
+	}
+
+	fmt.Println()
+	if len(themes) > 1 && total.maxPoints > 0 {
+		names := make([]string, 0, len(themes))
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			score := themes[name]
+			fmt.Printf("%-40s %4d/%d points (%.1f%%), %d/%d solved\n", name, score.points, score.maxPoints,
+				percent(score.points, score.maxPoints), score.solved, score.positions)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("solved %d/%d (%.1f%%), failed %d\n", total.solved, total.positions, percent(total.solved, total.positions), total.positions-total.solved)
+	if total.maxPoints > 0 {
+		fmt.Printf("points %d/%d (%.1f%%)\n", total.points, total.maxPoints, percent(total.points, total.maxPoints))
+	}
+}
+
+func percent(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(whole)
+}
+
+//one epd per line, empty lines and lines starting with # don't count
+func readSuite(path string) ([]amatriciana.EPD, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var suite []amatriciana.EPD
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		position, err := amatriciana.ParseEPD(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		suite = append(suite, position)
+	}
+
+	return suite, scanner.Err()
+}
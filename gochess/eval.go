@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"../amatriciana"
+)
+
+//gochess eval [-json] [-params <file>] [-nnue <file>] <fen>
+func eval(args []string) {
+	flags := flag.NewFlagSet("eval", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the evaluation as json")
+	paramsFile := flags.String("params", "", "a json or toml file with the evaluation weights")
+	networkFile := flags.String("nnue", "", "a network file to evaluate with instead of the weights")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		fmt.Println("usage: gochess eval [-json] [-params <file>] [-nnue <file>] <fen>")
+		os.Exit(1)
+	}
+
+	board, err := amatriciana.BoardFromFEN(strings.Join(flags.Args(), " "))
+	if err != nil {
+		fmt.Println("couldn't parse the fen:", err.Error())
+		os.Exit(1)
+	}
+
+	evaluator, err := loadEvaluator(*paramsFile, *networkFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	details := evaluator.EvaluateDetailed(board)
+
+	if *asJSON {
+		out, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			fmt.Println("couldn't encode the evaluation:", err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Println(board.Draw())
+	switch {
+	case details.Checkmate:
+		fmt.Println("checkmate")
+	case details.Endgame != "":
+		fmt.Println("known endgame:", details.Endgame)
+	default:
+		fmt.Printf("%-16s %8s %8s %8s %8s %8s\n", "term", "white mg", "white eg", "black mg", "black eg", "total")
+		for _, term := range details.Terms {
+			fmt.Printf("%-16s %8.2f %8.2f %8.2f %8.2f %8.2f\n", term.Name,
+				term.White.Middlegame.Pawns(), term.White.Endgame.Pawns(),
+				term.Black.Middlegame.Pawns(), term.Black.Endgame.Pawns(), term.Total.Pawns())
+		}
+		fmt.Printf("phase: %d/24\n", details.Phase)
+	}
+	fmt.Println("total:", details.Total)
+}
+
+//the network if there's one, otherwise the weights, the default ones if there's no file
+func loadEvaluator(paramsFile, networkFile string) (*amatriciana.Evaluator, error) {
+	if networkFile != "" {
+		network, err := amatriciana.LoadNetwork(networkFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load the network: %v", err)
+		}
+		return amatriciana.NewNetworkEvaluator(network), nil
+	}
+
+	params := amatriciana.DefaultEvalParams()
+	if paramsFile != "" {
+		var err error
+		params, err = amatriciana.LoadEvalParams(paramsFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load the weights: %v", err)
+		}
+	}
+	return amatriciana.NewEvaluator(params), nil
+}
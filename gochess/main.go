@@ -11,6 +11,45 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mate":
+			mate(os.Args[2:])
+			return
+		case "book":
+			book(os.Args[2:])
+			return
+		case "eval":
+			eval(os.Args[2:])
+			return
+		case "tune":
+			tune(os.Args[2:])
+			return
+		case "datagen":
+			datagen(os.Args[2:])
+			return
+		case "match":
+			match(os.Args[2:])
+			return
+		case "epd":
+			epd(os.Args[2:])
+			return
+		case "bench":
+			bench(os.Args[2:])
+			return
+		case "annotate":
+			annotate(os.Args[2:])
+			return
+		case "uci":
+			amatriciana.UCI(os.Stdin, os.Stdout)
+			return
+		}
+	}
+
+	play()
+}
+
+func play() {
 	//board := amatriciana.NewBoard()
 	//board, _ := amatriciana.BoardFromFEN("3k4/7R/R7/8/8/3K4/8/8 w - - 2 2")
 	board, _ := amatriciana.BoardFromFEN("4rkn1/p1Q2p1q/8/2pp4/5P2/1P4P1/PBbKB3/8 b - - 0 20")
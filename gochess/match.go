@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"../amatriciana"
+	"../amatriciana/uciclient"
+)
+
+//one game of the match, the first engine is white in the odd ones
+type matchJob struct {
+	number  int
+	opening amatriciana.Game
+}
+
+type matchResult struct {
+	number int
+	game   amatriciana.Game
+	reason string
+	err    error
+}
+
+//gochess match [-engine1 <spec>] [-engine2 <spec>] [-games <n>] [-tc <base+inc>] [-concurrency <n>] [-openings <file>]
+//[-random-plies <n>] [-seed <n>] [-pgn <file>] [-syzygy <path>] [-resign <cp>] [-resign-plies <n>] [-draw <cp>]
+//[-draw-plies <n>] [-draw-after <n>] [-sprt] [-elo0 <elo>] [-elo1 <elo>] [-alpha <p>] [-beta <p>]
+//
+//an engine is a comma separated list of settings: name, params, nnue, hash
+//and threads for this engine, or cmd with the path of a uci engine (and its
+//arguments after spaces) plus option.<name>=<value> for each option to set on it
+func match(args []string) {
+	flags := flag.NewFlagSet("match", flag.ExitOnError)
+	spec1 := flags.String("engine1", "name=engine1", "the first engine")
+	spec2 := flags.String("engine2", "name=engine2", "the second engine")
+	games := flags.Int("games", 100, "how many games to play, two for every opening")
+	tcString := flags.String("tc", "10+0.1", "the time control, seconds for the game plus seconds per move")
+	concurrency := flags.Int("concurrency", 1, "how many games get played at the same time")
+	openingsFile := flags.String("openings", "", "a pgn file or a file with a fen or an epd on every line")
+	randomPlies := flags.Int("random-plies", 8, "without an openings file, how many random moves the openings have")
+	seed := flags.Int64("seed", 0, "the seed for the random openings, 0 means the time")
+	pgnFile := flags.String("pgn", "match.pgn", "where the games go, nothing if empty")
+	syzygyPath := flags.String("syzygy", "", "the syzygy tablebases, to adjudicate with them")
+	defaults := amatriciana.DefaultAdjudication()
+	resignScore := flags.Int("resign", int(defaults.ResignScore), "the score in centipawns both engines have to agree on for a resignation")
+	resignPlies := flags.Int("resign-plies", defaults.ResignPlies, "for how many plies in a row, 0 means never")
+	drawScore := flags.Int("draw", int(defaults.DrawScore), "the score in centipawns both engines have to stay within for a draw")
+	drawPlies := flags.Int("draw-plies", defaults.DrawPlies, "for how many plies in a row, 0 means never")
+	drawAfter := flags.Int("draw-after", defaults.DrawAfter, "how many plies into the game a draw can be adjudicated")
+	useSPRT := flags.Bool("sprt", false, "stop as soon as the sprt accepts one of its hypotheses")
+	elo0 := flags.Float64("elo0", 0, "the elo difference of H0")
+	elo1 := flags.Float64("elo1", 5, "the elo difference of H1")
+	alpha := flags.Float64("alpha", 0.05, "the chance of accepting H1 when H0 is true")
+	beta := flags.Float64("beta", 0.05, "the chance of accepting H0 when H1 is true")
+	flags.Parse(args)
+
+	if *games < 1 || *concurrency < 1 || flags.NArg() != 0 {
+		fmt.Println("usage: gochess match [-engine1 <spec>] [-engine2 <spec>] [-games <n>] [-tc <base+inc>] [-concurrency <n>] [-openings <file>] [-pgn <file>] [-sprt] ...")
+		os.Exit(1)
+	}
+
+	tc, err := amatriciana.ParseTimeControl(*tcString)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	//every engine gets made once before the match, so a spec that doesn't
+	//work gets found before any game and the names are known
+	var names [2]string
+	for i, spec := range []string{*spec1, *spec2} {
+		player, err := newPlayer(spec)
+		if err != nil {
+			fmt.Printf("engine%d: %s\n", i+1, err.Error())
+			os.Exit(1)
+		}
+		names[i] = player.Name()
+		closePlayer(player)
+	}
+
+	if *syzygyPath != "" {
+		if err := amatriciana.SetSyzygyPath(*syzygyPath); err != nil {
+			fmt.Println("couldn't load the tablebases:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	var openings []amatriciana.Game
+	if *openingsFile != "" {
+		openings, err = readOpenings(*openingsFile)
+		if err != nil {
+			fmt.Println("couldn't read the openings:", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(*seed))
+
+	var pgn *bufio.Writer
+	if *pgnFile != "" {
+		file, err := os.Create(*pgnFile)
+		if err != nil {
+			fmt.Println("couldn't create the pgn:", err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		pgn = bufio.NewWriter(file)
+		defer pgn.Flush()
+	}
+
+	adjudication := amatriciana.Adjudication{
+		ResignScore: amatriciana.Score(*resignScore),
+		ResignPlies: *resignPlies,
+		DrawScore:   amatriciana.Score(*drawScore),
+		DrawPlies:   *drawPlies,
+		DrawAfter:   *drawAfter,
+		Tablebases:  *syzygyPath != "",
+	}
+	sprt := amatriciana.SPRT{Elo0: *elo0, Elo1: *elo1, Alpha: *alpha, Beta: *beta}
+
+	//both engines play every opening once with each color. closing stop
+	//means no more games get started
+	jobs := make(chan matchJob)
+	stop := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		var opening amatriciana.Game
+		for i := 0; i < *games; i++ {
+			if i%2 == 0 {
+				if len(openings) > 0 {
+					opening = openings[(i/2)%len(openings)]
+				} else {
+					opening = amatriciana.RandomOpening(random, *randomPlies)
+				}
+			}
+
+			select {
+			case jobs <- matchJob{i + 1, opening}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	results := make(chan matchResult)
+	for i := 0; i < *concurrency; i++ {
+		go func() {
+			players := [2]amatriciana.Player{}
+			for i, spec := range []string{*spec1, *spec2} {
+				player, err := newPlayer(spec)
+				if err != nil {
+					fmt.Printf("engine%d: %s\n", i+1, err.Error())
+					os.Exit(1)
+				}
+				players[i] = player
+				defer closePlayer(player)
+			}
+
+			for job := range jobs {
+				white, black := players[0], players[1]
+				if job.number%2 == 0 {
+					white, black = black, white
+				}
+
+				game, reason, err := amatriciana.PlayMatchGame(white, black, job.opening, tc, adjudication)
+				results <- matchResult{job.number, game, reason, err}
+			}
+			results <- matchResult{}
+		}()
+	}
+
+	var score amatriciana.MatchScore
+	for running := *concurrency; running > 0; {
+		result := <-results
+		if result.number == 0 {
+			running--
+			continue
+		}
+		if result.err != nil {
+			fmt.Printf("game %d couldn't be played: %s\n", result.number, result.err.Error())
+			continue
+		}
+
+		game := result.game
+		game.Tags["Event"] = "gochess match"
+		game.Tags["Date"] = time.Now().Format("2006.01.02")
+		game.Tags["Round"] = strconv.Itoa(result.number)
+		if pgn != nil {
+			if err := game.WritePGN(pgn); err != nil {
+				fmt.Println("couldn't write the pgn:", err.Error())
+			}
+		}
+
+		firstIsWhite := result.number%2 == 1
+		score.Add(game.Result, firstIsWhite)
+		elo, margin := score.Elo()
+		fmt.Printf("game %d: %s vs %s %s (%s)\n", result.number, game.Tags["White"], game.Tags["Black"], game.Result, result.reason)
+		fmt.Printf("score of %s vs %s: %d - %d - %d, elo %.1f +/- %.1f",
+			names[0], names[1], score.Wins, score.Losses, score.Draws, elo, margin)
+
+		if !*useSPRT {
+			fmt.Println()
+			continue
+		}
+		lower, upper := sprt.Bounds()
+		fmt.Printf(", llr %.2f (%.2f, %.2f)\n", sprt.LLR(score), lower, upper)
+		if verdict := sprt.Verdict(score); verdict != "" {
+			select {
+			case <-stop:
+			default:
+				fmt.Printf("sprt: %s accepted after %d games, waiting for the games that already started\n", verdict, score.Games())
+				close(stop)
+			}
+		}
+	}
+
+	if *useSPRT && sprt.Verdict(score) == "" {
+		fmt.Println("sprt: no hypothesis accepted yet")
+	}
+}
+
+//newPlayer makes the engine a spec describes
+func newPlayer(spec string) (amatriciana.Player, error) {
+	settings := map[string]string{"hash": "16", "threads": "1"}
+	var options [][2]string
+	for _, setting := range strings.Split(spec, ",") {
+		if strings.TrimSpace(setting) == "" {
+			continue
+		}
+		equals := strings.IndexByte(setting, '=')
+		if equals < 0 {
+			return nil, fmt.Errorf("%q isn't a setting like name=value", setting)
+		}
+
+		key, value := strings.TrimSpace(setting[:equals]), strings.TrimSpace(setting[equals+1:])
+		if strings.HasPrefix(key, "option.") {
+			options = append(options, [2]string{strings.TrimPrefix(key, "option."), value})
+			continue
+		}
+		settings[key] = value
+	}
+
+	if path := settings["cmd"]; path != "" {
+		name := settings["name"]
+		if name == "" {
+			name = path
+		}
+		return startUCIPlayer(name, path, options)
+	}
+	if len(options) > 0 {
+		return nil, errors.New("options are for uci engines, this engine takes params, nnue, hash and threads")
+	}
+
+	hash, err := strconv.Atoi(settings["hash"])
+	if err != nil || hash < 1 {
+		return nil, fmt.Errorf("%q isn't a hash size", settings["hash"])
+	}
+	threads, err := strconv.Atoi(settings["threads"])
+	if err != nil || threads < 1 {
+		return nil, fmt.Errorf("%q isn't a number of threads", settings["threads"])
+	}
+
+	evaluator, err := loadEvaluator(settings["params"], settings["nnue"])
+	if err != nil {
+		return nil, err
+	}
+
+	name := settings["name"]
+	if name == "" {
+		name = "amatriciana"
+	}
+	return amatriciana.NewEnginePlayer(name, evaluator, hash, threads), nil
+}
+
+func closePlayer(player amatriciana.Player) {
+	if closer, ok := player.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+//a pgn file, or a fen or an epd on every line. empty lines and lines
+//starting with # don't count
+func readOpenings(path string) ([]amatriciana.Game, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(strings.ToLower(path), ".pgn") {
+		return amatriciana.ReadPGN(file)
+	}
+
+	var openings []amatriciana.Game
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := amatriciana.ParseEPD(line)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", line, err)
+		}
+		openings = append(openings, amatriciana.Game{Tags: map[string]string{}, Start: e.Board})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(openings) == 0 {
+		return nil, errors.New("there are no openings in the file")
+	}
+
+	return openings, nil
+}
+
+//uciPlayer is an engine in another process that speaks uci
+type uciPlayer struct {
+	name   string
+	engine *uciclient.Engine
+}
+
+//command is the path of the engine and the arguments it needs, split by spaces
+func startUCIPlayer(name, command string, options [][2]string) (*uciPlayer, error) {
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return nil, errors.New("the engine's command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	engine, err := uciclient.Start(ctx, words[0], words[1:]...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		if err := engine.SetOption(option[0], option[1]); err != nil {
+			engine.Close()
+			return nil, err
+		}
+	}
+	if err := engine.IsReady(ctx); err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	return &uciPlayer{name, engine}, nil
+}
+
+func (p *uciPlayer) Name() string {
+	return p.name
+}
+
+func (p *uciPlayer) NewGame() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return p.engine.NewGame(ctx)
+}
+
+func (p *uciPlayer) Move(start amatriciana.Board, moves []string, clocks [2]time.Duration, tc amatriciana.TimeControl) (string, amatriciana.Score, error) {
+	if err := p.engine.PositionFrom(start, moves); err != nil {
+		return "", 0, err
+	}
+
+	//a bit more than the clock, so that running out of time is a loss and not an error
+	ctx, cancel := context.WithTimeout(context.Background(), clocks[0]+clocks[1]+5*time.Second)
+	defer cancel()
+
+	search, err := p.engine.Go(ctx, uciclient.Limits{
+		WTime: clocks[0],
+		BTime: clocks[1],
+		WInc:  tc.Increment,
+		BInc:  tc.Increment,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	best, err := search.Wait()
+	if err != nil {
+		return "", 0, err
+	}
+
+	var score amatriciana.Score
+	if best.Info.Score != nil {
+		score, _ = amatriciana.ParseScore(best.Info.Score.String())
+	}
+	return best.Move, score, nil
+}
+
+//Close asks the engine to quit
+func (p *uciPlayer) Close() error {
+	return p.engine.Close()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"../amatriciana"
+)
+
+//gochess mate [-checks] <n> <fen>
+func mate(args []string) {
+	flags := flag.NewFlagSet("mate", flag.ExitOnError)
+	checksOnly := flags.Bool("checks", false, "only try attacker moves that give check")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Println("usage: gochess mate [-checks] <n> <fen>")
+		os.Exit(1)
+	}
+
+	n, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		fmt.Println("n isn't a number:", err.Error())
+		os.Exit(1)
+	}
+
+	board, err := amatriciana.BoardFromFEN(strings.Join(flags.Args()[1:], " "))
+	if err != nil {
+		fmt.Println("couldn't parse the fen:", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println(board.Draw())
+	solution := board.SolveMateWithOptions(n, amatriciana.MateOptions{ChecksOnly: *checksOnly})
+
+	if !solution.Found {
+		if *checksOnly {
+			fmt.Printf("no checking mate in %d (quiet keys weren't tried)\n", n)
+		} else {
+			fmt.Printf("there is no forced mate in %d\n", n)
+		}
+	} else {
+		fmt.Printf("%s mates in %d\n", board.Turn(), n)
+		for _, key := range solution.Keys {
+			fmt.Println("key:", key.UCIString())
+		}
+		if solution.Cooked() {
+			fmt.Println("the problem is cooked:", len(solution.Keys), "keys")
+		}
+	}
+
+	fmt.Println("positions searched:", solution.Nodes)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"../amatriciana"
+)
+
+//gochess tune [-params <file>] [-out <file>] [-passes <n>] [-step <n>] [-threads <n>] [-only <names>] <dataset.epd>
+func tune(args []string) {
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	paramsFile := flags.String("params", "", "the weights to start from, json or toml")
+	outFile := flags.String("out", "tuned.json", "where the tuned weights go, json or toml")
+	passes := flags.Int("passes", 0, "the most passes over the weights, 0 means until nothing improves")
+	step := flags.Int("step", 1, "how much a weight changes at a time, in centipawns")
+	threads := flags.Int("threads", runtime.NumCPU(), "how many goroutines evaluate positions")
+	only := flags.String("only", "", "comma separated names of the weights to tune, all of them if empty")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Println("usage: gochess tune [-params <file>] [-out <file>] [-passes <n>] [-step <n>] [-threads <n>] [-only <names>] <dataset.epd>")
+		os.Exit(1)
+	}
+
+	params := amatriciana.DefaultEvalParams()
+	if *paramsFile != "" {
+		var err error
+		params, err = amatriciana.LoadEvalParams(*paramsFile)
+		if err != nil {
+			fmt.Println("couldn't load the weights:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	positions, skipped, err := readDataset(flags.Arg(0))
+	if err != nil {
+		fmt.Println("couldn't read the dataset:", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("positions:", len(positions))
+	if skipped > 0 {
+		fmt.Println("lines skipped because they couldn't be parsed:", skipped)
+	}
+	if len(positions) == 0 {
+		os.Exit(1)
+	}
+
+	opts := amatriciana.TuneOptions{
+		Threads: *threads,
+		Passes:  *passes,
+		Step:    *step,
+		Info: func(info amatriciana.TuneInfo) {
+			fmt.Printf("pass %d: error %.6f, %d weights changed\n", info.Pass, info.Error, info.Changed)
+		},
+	}
+	if *only != "" {
+		opts.Only = strings.Split(*only, ",")
+	}
+
+	tuned, k, finalError := amatriciana.TuneEvalParams(positions, params, opts)
+	fmt.Printf("K: %.4f, error: %.6f\n", k, finalError)
+
+	err = tuned.Save(*outFile)
+	if err != nil {
+		fmt.Println("couldn't save the weights:", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("tuned weights written to", *outFile)
+}
+
+//one position per line, empty lines and lines starting with # don't count
+func readDataset(path string) ([]amatriciana.TuningPosition, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var positions []amatriciana.TuningPosition
+	skipped := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		position, err := amatriciana.ParseTuningPosition(line)
+		if err != nil {
+			skipped++
+			continue
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, skipped, scanner.Err()
+}